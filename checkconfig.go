@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/mikrotik-exporter/config"
+)
+
+// checkConfig implements the "check-config" command. It loads and validates
+// the configuration file and returns the process exit code.
+func checkConfig(args []string) int {
+	flags := flag.NewFlagSet("check-config", flag.ContinueOnError)
+	configFile := flags.String("config.file", getEnv("CONFIG_FILE", "./config.yaml"), "Configuration file to check")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	// Metric names appear in errors about conflicts
+	metricsNamespace = getEnv("METRICS_NAMESPACE", "mikrotik_exporter")
+	registerCollectors()
+
+	conf, err := config.LoadConfig(*configFile)
+	if err == nil {
+		var warnings []string
+		warnings, err = conf.Validate(collectorCatalog{collectorRegistry})
+		for _, warning := range warnings {
+			fmt.Fprintf(os.Stderr, "%s: warning: %s\n", *configFile, warning)
+		}
+	}
+
+	if err != nil {
+		var errs config.ValidationErrors
+		if errors.As(err, &errs) {
+			for _, e := range errs {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", *configFile, e.Error())
+			}
+		} else {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", *configFile, err)
+		}
+		return 1
+	}
+
+	fmt.Printf("%s: configuration is valid\n", *configFile)
+	return 0
+}
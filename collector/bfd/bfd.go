@@ -0,0 +1,247 @@
+package bfd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/mikrotik-exporter/collector"
+	"github.com/mikrotik-exporter/config"
+	"github.com/mikrotik-exporter/routeros"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector implements the collector.Collector interface for BFD metrics
+type Collector struct {
+	sessionUpDesc          *prometheus.Desc
+	sessionStateDesc       *prometheus.Desc
+	uptimeDesc             *prometheus.Desc
+	stateChangesDesc       *prometheus.Desc
+	actualTxIntervalDesc   *prometheus.Desc
+	requiredMinRxDesc      *prometheus.Desc
+	remoteMinRxDesc        *prometheus.Desc
+	multiplierDesc         *prometheus.Desc
+	packetsReceivedDesc    *prometheus.Desc
+	packetsTransmittedDesc *prometheus.Desc
+	namespace              string
+}
+
+// BFDSessionData represents the structure returned by the Mikrotik BFD session API (RouterOS 7)
+type BFDSessionData struct {
+	ID                string `json:".id"`
+	RemoteAddress     string `json:"remote-address"`
+	LocalAddress      string `json:"local-address"`
+	Interface         string `json:"interface"`
+	VRF               string `json:"vrf"`
+	Multihop          string `json:"multihop"`
+	State             string `json:"state"`
+	StateChanges      string `json:"state-changes"`
+	Uptime            string `json:"uptime"`
+	ActualTxInterval  string `json:"actual-tx-interval"`
+	DesiredTxInterval string `json:"desired-tx-interval"`
+	RequiredMinRx     string `json:"required-min-rx"`
+	RemoteMinRx       string `json:"remote-min-rx"`
+	Multiplier        string `json:"multiplier"`
+	PacketsRx         string `json:"packets-rx"`
+	PacketsTx         string `json:"packets-tx"`
+}
+
+// sessionStates maps the session states to their value in RFC 5880
+var sessionStates = map[string]float64{
+	"admin-down": 0,
+	"down":       1,
+	"init":       2,
+	"up":         3,
+}
+
+// NewCollector creates a new BFD collector
+func NewCollector() *Collector {
+	c := &Collector{
+		namespace: "mikrotik_exporter", // default namespace
+	}
+	c.initMetrics()
+	return c
+}
+
+// initMetrics initializes the metric descriptors with the current namespace
+func (c *Collector) initMetrics() {
+	labels := []string{"remote_address", "local_address", "interface", "vrf"}
+
+	c.sessionUpDesc = prometheus.NewDesc(
+		c.namespace+"_bfd_session_up",
+		"BFD session status (1 = up, 0 = not up)",
+		labels,
+		nil,
+	)
+	c.sessionStateDesc = prometheus.NewDesc(
+		c.namespace+"_bfd_session_state",
+		"BFD session state as in RFC 5880 (0 = AdminDown, 1 = Down, 2 = Init, 3 = Up)",
+		labels,
+		nil,
+	)
+	c.uptimeDesc = prometheus.NewDesc(
+		c.namespace+"_bfd_session_uptime_seconds",
+		"BFD session uptime in seconds",
+		labels,
+		nil,
+	)
+	c.stateChangesDesc = prometheus.NewDesc(
+		c.namespace+"_bfd_session_state_changes_total",
+		"Number of BFD session state changes (up/down transitions)",
+		labels,
+		nil,
+	)
+	c.actualTxIntervalDesc = prometheus.NewDesc(
+		c.namespace+"_bfd_session_actual_tx_interval_seconds",
+		"Negotiated interval between transmitted BFD packets in seconds",
+		labels,
+		nil,
+	)
+	c.requiredMinRxDesc = prometheus.NewDesc(
+		c.namespace+"_bfd_session_required_min_rx_interval_seconds",
+		"Minimum interval between received BFD packets this system supports in seconds",
+		labels,
+		nil,
+	)
+	c.remoteMinRxDesc = prometheus.NewDesc(
+		c.namespace+"_bfd_session_remote_min_rx_interval_seconds",
+		"Minimum interval between received BFD packets the remote system supports in seconds",
+		labels,
+		nil,
+	)
+	c.multiplierDesc = prometheus.NewDesc(
+		c.namespace+"_bfd_session_multiplier",
+		"BFD detection time multiplier",
+		labels,
+		nil,
+	)
+	c.packetsReceivedDesc = prometheus.NewDesc(
+		c.namespace+"_bfd_session_packets_received_total",
+		"Number of BFD packets received",
+		labels,
+		nil,
+	)
+	c.packetsTransmittedDesc = prometheus.NewDesc(
+		c.namespace+"_bfd_session_packets_transmitted_total",
+		"Number of BFD packets transmitted",
+		labels,
+		nil,
+	)
+}
+
+// Name returns the collector name
+func (c *Collector) Name() string {
+	return "bfd"
+}
+
+// Describe sends the descriptors of each metric over to the provided channel
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.sessionUpDesc
+	ch <- c.sessionStateDesc
+	ch <- c.uptimeDesc
+	ch <- c.stateChangesDesc
+	ch <- c.actualTxIntervalDesc
+	ch <- c.requiredMinRxDesc
+	ch <- c.remoteMinRxDesc
+	ch <- c.multiplierDesc
+	ch <- c.packetsReceivedDesc
+	ch <- c.packetsTransmittedDesc
+}
+
+// SetNamespace sets the metrics namespace prefix
+func (c *Collector) SetNamespace(namespace string) {
+	c.namespace = namespace
+	c.initMetrics()
+}
+
+// Configure returns the collector; it has no options
+func (c *Collector) Configure(options config.CollectorOptions) (collector.Collector, error) {
+	if err := collector.NoOptions(options); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Collect fetches the metrics from Mikrotik device and sends them to Prometheus
+func (c *Collector) Collect(ctx context.Context, client *routeros.Client, ch chan<- prometheus.Metric) error {
+	sessions, err := c.fetchSessions(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to fetch BFD sessions: %w", err)
+	}
+
+	for _, session := range sessions {
+		labels := []string{session.RemoteAddress, session.LocalAddress, session.Interface, session.VRF}
+
+		// Session state
+		upValue := 0.0
+		if session.State == "up" {
+			upValue = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(c.sessionUpDesc, prometheus.GaugeValue, upValue, labels...)
+
+		if state, ok := sessionStates[session.State]; ok {
+			ch <- prometheus.MustNewConstMetric(c.sessionStateDesc, prometheus.GaugeValue, state, labels...)
+		} else {
+			client.ParseError("state", session.State, fmt.Errorf("unknown state"))
+		}
+
+		// Uptime is only reported while the session is up
+		if uptime, err := routeros.ParseDuration(session.Uptime); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.uptimeDesc, prometheus.GaugeValue, uptime.Seconds(), labels...)
+		} else {
+			client.ParseError("uptime", session.Uptime, err)
+		}
+
+		if changes, err := strconv.ParseFloat(session.StateChanges, 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.stateChangesDesc, prometheus.CounterValue, changes, labels...)
+		} else {
+			client.ParseError("state-changes", session.StateChanges, err)
+		}
+
+		// Timers
+		if interval, err := routeros.ParseDuration(session.ActualTxInterval); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.actualTxIntervalDesc, prometheus.GaugeValue, interval.Seconds(), labels...)
+		} else {
+			client.ParseError("actual-tx-interval", session.ActualTxInterval, err)
+		}
+		if interval, err := routeros.ParseDuration(session.RequiredMinRx); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.requiredMinRxDesc, prometheus.GaugeValue, interval.Seconds(), labels...)
+		} else {
+			client.ParseError("required-min-rx", session.RequiredMinRx, err)
+		}
+		if interval, err := routeros.ParseDuration(session.RemoteMinRx); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.remoteMinRxDesc, prometheus.GaugeValue, interval.Seconds(), labels...)
+		} else {
+			client.ParseError("remote-min-rx", session.RemoteMinRx, err)
+		}
+		if multiplier, err := strconv.ParseFloat(session.Multiplier, 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.multiplierDesc, prometheus.GaugeValue, multiplier, labels...)
+		} else {
+			client.ParseError("multiplier", session.Multiplier, err)
+		}
+
+		// Packet counters
+		if packets, err := strconv.ParseFloat(session.PacketsRx, 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.packetsReceivedDesc, prometheus.CounterValue, packets, labels...)
+		} else {
+			client.ParseError("packets-rx", session.PacketsRx, err)
+		}
+		if packets, err := strconv.ParseFloat(session.PacketsTx, 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.packetsTransmittedDesc, prometheus.CounterValue, packets, labels...)
+		} else {
+			client.ParseError("packets-tx", session.PacketsTx, err)
+		}
+	}
+
+	return nil
+}
+
+// fetchSessions fetches the BFD sessions
+func (c *Collector) fetchSessions(ctx context.Context, client *routeros.Client) ([]BFDSessionData, error) {
+	var sessions []BFDSessionData
+	if err := client.Get(ctx, "/routing/bfd/session", &sessions); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
@@ -2,14 +2,12 @@ package bgp
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
-	"regexp"
 	"strconv"
-	"time"
 
 	"github.com/mikrotik-exporter/collector"
+	"github.com/mikrotik-exporter/config"
+	"github.com/mikrotik-exporter/routeros"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -65,6 +63,42 @@ type BGPSessionData struct {
 	Uptime             string `json:"uptime"`
 }
 
+// BGPPeerDataV6 represents the structure returned by the RouterOS 6 BGP peer API
+type BGPPeerDataV6 struct {
+	ID            string `json:".id"`
+	Disabled      string `json:"disabled"`
+	Established   string `json:"established"`
+	Instance      string `json:"instance"`
+	LocalAddress  string `json:"local-address"`
+	Name          string `json:"name"`
+	PrefixCount   string `json:"prefix-count"`
+	RemoteAddress string `json:"remote-address"`
+	RemoteAS      string `json:"remote-as"`
+	RemoteID      string `json:"remote-id"`
+	State         string `json:"state"`
+	Uptime        string `json:"uptime"`
+}
+
+// toSession maps a RouterOS 6 peer to the RouterOS 7 session structure.
+// Byte and message counters are not available on RouterOS 6.
+func (p BGPPeerDataV6) toSession() BGPSessionData {
+	established := p.Established
+	if established == "" && p.State == "established" {
+		established = "true"
+	}
+	return BGPSessionData{
+		ID:            p.ID,
+		Established:   established,
+		LocalAddress:  p.LocalAddress,
+		Name:          p.Name,
+		PrefixCount:   p.PrefixCount,
+		RemoteAddress: p.RemoteAddress,
+		RemoteAS:      p.RemoteAS,
+		RemoteID:      p.RemoteID,
+		Uptime:        p.Uptime,
+	}
+}
+
 // NewCollector creates a new BGP collector
 func NewCollector() *Collector {
 	c := &Collector{
@@ -149,10 +183,18 @@ func (c *Collector) SetNamespace(namespace string) {
 	c.initMetrics()
 }
 
+// Configure returns the collector; it has no options
+func (c *Collector) Configure(options config.CollectorOptions) (collector.Collector, error) {
+	if err := collector.NoOptions(options); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
 // Collect fetches the metrics from Mikrotik device and sends them to Prometheus
-func (c *Collector) Collect(ctx context.Context, target string, auth collector.AuthInfo, ch chan<- prometheus.Metric) error {
+func (c *Collector) Collect(ctx context.Context, client *routeros.Client, ch chan<- prometheus.Metric) error {
 	// Fetch BGP session data from Mikrotik REST API
-	sessions, err := c.fetchBGPSessions(ctx, target, auth)
+	sessions, err := c.fetchBGPSessions(ctx, client)
 	if err != nil {
 		return fmt.Errorf("failed to fetch BGP sessions: %w", err)
 	}
@@ -176,27 +218,39 @@ func (c *Collector) Collect(ctx context.Context, target string, auth collector.A
 		// Prefix count
 		if prefixCount, err := c.parseNumericField(session.PrefixCount); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.prefixCountDesc, prometheus.GaugeValue, prefixCount, labels...)
+		} else {
+			client.ParseError("prefix-count", session.PrefixCount, err)
 		}
 
 		// Remote bytes and messages
 		if remoteBytes, err := c.parseNumericField(session.RemoteBytes); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.remoteBytesTotalDesc, prometheus.CounterValue, remoteBytes, labels...)
+		} else {
+			client.ParseError("remote.bytes", session.RemoteBytes, err)
 		}
 		if remoteMessages, err := c.parseNumericField(session.RemoteMessages); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.remoteMessagesTotalDesc, prometheus.CounterValue, remoteMessages, labels...)
+		} else {
+			client.ParseError("remote.messages", session.RemoteMessages, err)
 		}
 
 		// Local bytes and messages
 		if localBytes, err := c.parseNumericField(session.LocalBytes); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.localBytesDesc, prometheus.CounterValue, localBytes, labels...)
+		} else {
+			client.ParseError("local.bytes", session.LocalBytes, err)
 		}
 		if localMessages, err := c.parseNumericField(session.LocalMessages); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.localMessagesTotalDesc, prometheus.CounterValue, localMessages, labels...)
+		} else {
+			client.ParseError("local.messages", session.LocalMessages, err)
 		}
 
 		// Uptime
-		if uptime, err := c.parseUptime(session.Uptime); err == nil && uptime > 0 {
-			ch <- prometheus.MustNewConstMetric(c.uptimeDesc, prometheus.GaugeValue, uptime, labels...)
+		if uptime, err := routeros.ParseDuration(session.Uptime); err != nil {
+			client.ParseError("uptime", session.Uptime, err)
+		} else if uptime > 0 {
+			ch <- prometheus.MustNewConstMetric(c.uptimeDesc, prometheus.GaugeValue, uptime.Seconds(), labels...)
 		}
 
 		// Session info
@@ -215,34 +269,31 @@ func (c *Collector) Collect(ctx context.Context, target string, auth collector.A
 	return nil
 }
 
-// fetchBGPSessions fetches BGP session data from Mikrotik REST API
-func (c *Collector) fetchBGPSessions(ctx context.Context, target string, auth collector.AuthInfo) ([]BGPSessionData, error) {
-	url := fmt.Sprintf("http://%s/rest/routing/bgp/session", target)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// fetchBGPSessions fetches BGP session data, using /routing/bgp/peer on RouterOS 6
+func (c *Collector) fetchBGPSessions(ctx context.Context, client *routeros.Client) ([]BGPSessionData, error) {
+	version, err := client.Version(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	req.SetBasicAuth(auth.Username, auth.Password)
-	req.Header.Set("Accept", "application/json")
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+	if version.Major < 7 {
+		var peers []BGPPeerDataV6
+		if err := client.Get(ctx, "/routing/bgp/peer", &peers); err != nil {
+			return nil, err
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+		sessions := make([]BGPSessionData, 0, len(peers))
+		for _, peer := range peers {
+			if peer.Disabled == "true" {
+				continue
+			}
+			sessions = append(sessions, peer.toSession())
+		}
+		return sessions, nil
 	}
 
 	var sessions []BGPSessionData
-	if err := json.NewDecoder(resp.Body).Decode(&sessions); err != nil {
+	if err := client.Get(ctx, "/routing/bgp/session", &sessions); err != nil {
 		return nil, err
 	}
 
@@ -256,60 +307,3 @@ func (c *Collector) parseNumericField(value string) (float64, error) {
 	}
 	return strconv.ParseFloat(value, 64)
 }
-
-// parseUptime parses Mikrotik uptime format to seconds
-func (c *Collector) parseUptime(uptime string) (float64, error) {
-	if uptime == "" {
-		return 0, fmt.Errorf("empty uptime")
-	}
-
-	// Remove milliseconds part if present (e.g., "2w4d36m5s950ms" -> "2w4d36m5s")
-	uptime = regexp.MustCompile(`\d+ms$`).ReplaceAllString(uptime, "")
-
-	// Parse uptime format: 2w4d1h12m27s
-	re := regexp.MustCompile(`(?:(\d+)w)?(?:(\d+)d)?(?:(\d+)h)?(?:(\d+)m)?(?:(\d+)s)?`)
-	matches := re.FindStringSubmatch(uptime)
-
-	if len(matches) == 0 {
-		return 0, fmt.Errorf("invalid uptime format: %s", uptime)
-	}
-
-	var totalSeconds float64
-
-	// Parse weeks
-	if matches[1] != "" {
-		if weeks, err := strconv.Atoi(matches[1]); err == nil {
-			totalSeconds += float64(weeks * 7 * 24 * 3600)
-		}
-	}
-
-	// Parse days
-	if matches[2] != "" {
-		if days, err := strconv.Atoi(matches[2]); err == nil {
-			totalSeconds += float64(days * 24 * 3600)
-		}
-	}
-
-	// Parse hours
-	if matches[3] != "" {
-		if hours, err := strconv.Atoi(matches[3]); err == nil {
-			totalSeconds += float64(hours * 3600)
-		}
-	}
-
-	// Parse minutes
-	if matches[4] != "" {
-		if minutes, err := strconv.Atoi(matches[4]); err == nil {
-			totalSeconds += float64(minutes * 60)
-		}
-	}
-
-	// Parse seconds
-	if matches[5] != "" {
-		if seconds, err := strconv.Atoi(matches[5]); err == nil {
-			totalSeconds += float64(seconds)
-		}
-	}
-
-	return totalSeconds, nil
-}
@@ -3,7 +3,10 @@ package collector
 import (
 	"context"
 	"fmt"
+	"sort"
 
+	"github.com/mikrotik-exporter/config"
+	"github.com/mikrotik-exporter/routeros"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -16,16 +19,14 @@ type Collector interface {
 	Describe(ch chan<- *prometheus.Desc)
 
 	// Collect fetches the metrics from Mikrotik device and sends them to Prometheus
-	Collect(ctx context.Context, target string, auth AuthInfo, ch chan<- prometheus.Metric) error
+	Collect(ctx context.Context, client *routeros.Client, ch chan<- prometheus.Metric) error
 
 	// SetNamespace sets the metrics namespace prefix
 	SetNamespace(namespace string)
-}
 
-// AuthInfo contains authentication information for connecting to Mikrotik device
-type AuthInfo struct {
-	Username string
-	Password string
+	// Configure returns a copy of the collector using the options of a module.
+	// Unknown or invalid options are reported as an error.
+	Configure(options config.CollectorOptions) (Collector, error)
 }
 
 // Registry holds all available collectors
@@ -54,24 +55,55 @@ func (r *Registry) Get(name string) (Collector, error) {
 	return collector, nil
 }
 
-// GetEnabled returns all enabled collectors based on the module configuration
-func (r *Registry) GetEnabled(enabledCollectors map[string]bool) []Collector {
+// GetEnabled returns all enabled collectors based on the module configuration,
+// configured with their module options
+func (r *Registry) GetEnabled(enabledCollectors map[string]config.CollectorConfig) ([]Collector, error) {
+	// Sorted so that the collectors, and the errors they report, keep their order
+	names := make([]string, 0, len(enabledCollectors))
+	for name := range enabledCollectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
 	var enabled []Collector
-	for name, isEnabled := range enabledCollectors {
-		if isEnabled {
+	for _, name := range names {
+		if collectorConfig := enabledCollectors[name]; collectorConfig.Enabled {
 			if collector, exists := r.collectors[name]; exists {
-				enabled = append(enabled, collector)
+				configured, err := collector.Configure(collectorConfig.Options)
+				if err != nil {
+					return nil, fmt.Errorf("collector '%s': %w", name, err)
+				}
+				enabled = append(enabled, configured)
 			}
 		}
 	}
-	return enabled
+	return enabled, nil
+}
+
+// ValidateOptions checks the module options of the named collector
+func (r *Registry) ValidateOptions(name string, options config.CollectorOptions) error {
+	collector, err := r.Get(name)
+	if err != nil {
+		return err
+	}
+	_, err = collector.Configure(options)
+	return err
 }
 
-// List returns all available collector names
+// List returns all available collector names in sorted order
 func (r *Registry) List() []string {
 	var names []string
 	for name := range r.collectors {
 		names = append(names, name)
 	}
+	sort.Strings(names)
 	return names
 }
+
+// NoOptions returns an error for collectors without options if any are set
+func NoOptions(options config.CollectorOptions) error {
+	for name := range options {
+		return fmt.Errorf("unknown option '%s' (the collector has no options)", name)
+	}
+	return nil
+}
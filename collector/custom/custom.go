@@ -0,0 +1,282 @@
+package custom
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/mikrotik-exporter/collector"
+	"github.com/mikrotik-exporter/config"
+	"github.com/mikrotik-exporter/routeros"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector implements the collector.Collector interface for a menu defined
+// in the custom_collectors section of the configuration
+type Collector struct {
+	name       string
+	config     config.CustomCollectorConfig
+	labelNames []string
+	descs      []*prometheus.Desc
+	namespace  string
+}
+
+// sample is the value of a metric for one set of label values
+type sample struct {
+	labels []string
+	value  float64
+}
+
+// NewCollector creates a collector from its configuration
+func NewCollector(name string, conf config.CustomCollectorConfig) *Collector {
+	labelNames := make([]string, 0, len(conf.Labels))
+	for label := range conf.Labels {
+		labelNames = append(labelNames, label)
+	}
+	sort.Strings(labelNames)
+
+	c := &Collector{
+		name:       name,
+		config:     conf,
+		labelNames: labelNames,
+		namespace:  "mikrotik_exporter", // default namespace
+	}
+	c.initMetrics()
+	return c
+}
+
+// initMetrics initializes the metric descriptors with the current namespace
+func (c *Collector) initMetrics() {
+	c.descs = make([]*prometheus.Desc, len(c.config.Metrics))
+	for i, metric := range c.config.Metrics {
+		labels := c.labelNames
+		if metric.Transform == config.TransformRxTx {
+			labels = append(append([]string(nil), labels...), config.DirectionLabel)
+		}
+
+		help := metric.Help
+		if help == "" {
+			help = fmt.Sprintf("Property %s of %s", metric.Property, c.config.Path)
+			if metric.Property == "" {
+				help = fmt.Sprintf("Number of entries in %s", c.config.Path)
+			}
+		}
+
+		c.descs[i] = prometheus.NewDesc(c.namespace+"_"+metric.Name, help, labels, nil)
+	}
+}
+
+// Name returns the collector name
+func (c *Collector) Name() string {
+	return c.name
+}
+
+// Describe sends the descriptors of each metric over to the provided channel
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range c.descs {
+		ch <- desc
+	}
+}
+
+// SetNamespace sets the metrics namespace prefix
+func (c *Collector) SetNamespace(namespace string) {
+	c.namespace = namespace
+	c.initMetrics()
+}
+
+// Configure returns the collector; it has no options
+func (c *Collector) Configure(options config.CollectorOptions) (collector.Collector, error) {
+	if err := collector.NoOptions(options); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Collect fetches the metrics from Mikrotik device and sends them to Prometheus
+func (c *Collector) Collect(ctx context.Context, client *routeros.Client, ch chan<- prometheus.Metric) error {
+	entries, err := c.fetchEntries(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to fetch %s: %w", c.config.Path, err)
+	}
+
+	for i, metric := range c.config.Metrics {
+		valueType := prometheus.GaugeValue
+		if metric.GetType() == config.MetricTypeCounter {
+			valueType = prometheus.CounterValue
+		}
+
+		// Entries with the same label values are added up
+		var samples []*sample
+		index := make(map[string]*sample)
+		add := func(labels []string, value float64) {
+			key := strings.Join(labels, "\xff")
+			if s, exists := index[key]; exists {
+				s.value += value
+				return
+			}
+			s := &sample{labels: labels, value: value}
+			index[key] = s
+			samples = append(samples, s)
+		}
+
+		for _, entry := range entries {
+			labels := make([]string, len(c.labelNames))
+			for j, label := range c.labelNames {
+				labels[j] = property(entry, c.config.Labels[label])
+			}
+
+			if metric.Property == "" {
+				add(labels, 1)
+				continue
+			}
+
+			raw := property(entry, metric.Property)
+			if metric.Transform == config.TransformRxTx {
+				rx, tx, err := parseRxTx(raw)
+				if err != nil {
+					client.ParseError(metric.Property, raw, err)
+					continue
+				}
+				add(append(append([]string(nil), labels...), "rx"), rx)
+				add(append(append([]string(nil), labels...), "tx"), tx)
+				continue
+			}
+
+			value, err := parseValue(raw, metric.Transform)
+			if err != nil {
+				client.ParseError(metric.Property, raw, err)
+				continue
+			}
+			add(labels, value)
+		}
+
+		for _, s := range samples {
+			ch <- prometheus.MustNewConstMetric(c.descs[i], valueType, s.value, s.labels...)
+		}
+	}
+
+	return nil
+}
+
+// fetchEntries fetches the entries of the menu. Menus with a single entry
+// such as /system/resource return an object instead of a list.
+func (c *Collector) fetchEntries(ctx context.Context, client *routeros.Client) ([]map[string]interface{}, error) {
+	opts := []routeros.RequestOption{}
+	if proplist := c.config.Proplist(); len(proplist) > 0 {
+		opts = append(opts, routeros.Proplist(proplist...))
+	}
+	for property, value := range c.config.Filter {
+		opts = append(opts, routeros.Where(property, value))
+	}
+
+	var body json.RawMessage
+	if err := client.Get(ctx, c.config.Path, &body, opts...); err != nil {
+		return nil, err
+	}
+
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '{' {
+		var entry map[string]interface{}
+		if err := json.Unmarshal(body, &entry); err != nil {
+			return nil, fmt.Errorf("failed to decode response: %w", err)
+		}
+		return []map[string]interface{}{entry}, nil
+	}
+
+	var entries []map[string]interface{}
+	if err := json.Unmarshal(body, &entries); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return entries, nil
+}
+
+// property returns a property of an entry as a string
+func property(entry map[string]interface{}, name string) string {
+	switch value := entry[name].(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// parseValue converts a property value to a number with the given transform
+func parseValue(value, transform string) (float64, error) {
+	if value == "" {
+		return 0, fmt.Errorf("empty value")
+	}
+
+	switch transform {
+	case config.TransformBool:
+		switch strings.ToLower(value) {
+		case "true", "yes":
+			return 1, nil
+		case "false", "no":
+			return 0, nil
+		}
+		return 0, fmt.Errorf("invalid boolean: %s", value)
+	case config.TransformDuration:
+		duration, err := routeros.ParseDuration(value)
+		if err != nil {
+			return 0, err
+		}
+		return duration.Seconds(), nil
+	case config.TransformBytes:
+		return parseQuantity(value)
+	default:
+		return strconv.ParseFloat(value, 64)
+	}
+}
+
+// parseRxTx parses "rx/tx" pairs such as "1500/3000" or "10M/20M"
+func parseRxTx(value string) (float64, float64, error) {
+	rxValue, txValue, found := strings.Cut(value, "/")
+	if !found {
+		return 0, 0, fmt.Errorf("invalid rx/tx pair: %s", value)
+	}
+	rx, err := parseQuantity(rxValue)
+	if err != nil {
+		return 0, 0, err
+	}
+	tx, err := parseQuantity(txValue)
+	if err != nil {
+		return 0, 0, err
+	}
+	return rx, tx, nil
+}
+
+// quantityRegexp matches numbers with an optional decimal (k, M, G, T) or
+// binary (KiB, MiB, GiB, TiB) unit, or a plain byte count such as "1500B"
+var quantityRegexp = regexp.MustCompile(`^(-?\d+(?:\.\d+)?)\s*(?:([kKMGT])(i?)B?|B)?$`)
+
+// unitExponents are the powers of the unit prefixes
+var unitExponents = map[string]int{"k": 1, "K": 1, "M": 2, "G": 3, "T": 4}
+
+// parseQuantity parses a number with an optional unit, e.g. "10M" or "1.5GiB"
+func parseQuantity(value string) (float64, error) {
+	matches := quantityRegexp.FindStringSubmatch(strings.TrimSpace(value))
+	if matches == nil {
+		return 0, fmt.Errorf("invalid quantity: %s", value)
+	}
+
+	number, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, err
+	}
+
+	base := 1000.0
+	if matches[3] == "i" {
+		base = 1024
+	}
+	for i := 0; i < unitExponents[matches[2]]; i++ {
+		number *= base
+	}
+	return number, nil
+}
@@ -0,0 +1,124 @@
+package custom
+
+import (
+	"testing"
+
+	"github.com/mikrotik-exporter/config"
+)
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{value: "1500", want: 1500},
+		{value: "1500B", want: 1500},
+		{value: "-3", want: -3},
+		{value: "1.5k", want: 1500},
+		{value: "10M", want: 10e6},
+		{value: "1.5KiB", want: 1536},
+		{value: "2 MiB", want: 2 * 1024 * 1024},
+		{value: "1GB", want: 1e9},
+		{value: " 4T ", want: 4e12},
+		{value: "", wantErr: true},
+		{value: "B", wantErr: true},
+		{value: "1500iB", wantErr: true},
+		{value: "10X", wantErr: true},
+		{value: "1.5.2k", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseQuantity(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseQuantity(%q) = %v, want error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseQuantity(%q) error = %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseQuantity(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := []struct {
+		value     string
+		transform string
+		want      float64
+		wantErr   bool
+	}{
+		{value: "42", want: 42},
+		{value: "-1.25", want: -1.25},
+		{value: "10M", wantErr: true},
+		{value: "", wantErr: true},
+		{value: "true", transform: config.TransformBool, want: 1},
+		{value: "yes", transform: config.TransformBool, want: 1},
+		{value: "False", transform: config.TransformBool, want: 0},
+		{value: "no", transform: config.TransformBool, want: 0},
+		{value: "maybe", transform: config.TransformBool, wantErr: true},
+		{value: "1w2d3h4m5s", transform: config.TransformDuration, want: 788645},
+		{value: "500ms", transform: config.TransformDuration, want: 0.5},
+		{value: "1d02:03:04", transform: config.TransformDuration, want: 93784},
+		{value: "soon", transform: config.TransformDuration, wantErr: true},
+		{value: "1.5KiB", transform: config.TransformBytes, want: 1536},
+		{value: "1500B", transform: config.TransformBytes, want: 1500},
+		{value: "many", transform: config.TransformBytes, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseValue(tt.value, tt.transform)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseValue(%q, %q) = %v, want error", tt.value, tt.transform, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseValue(%q, %q) error = %v", tt.value, tt.transform, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseValue(%q, %q) = %v, want %v", tt.value, tt.transform, got, tt.want)
+		}
+	}
+}
+
+func TestParseRxTx(t *testing.T) {
+	tests := []struct {
+		value   string
+		rx      float64
+		tx      float64
+		wantErr bool
+	}{
+		{value: "1500/3000", rx: 1500, tx: 3000},
+		{value: "10M/20M", rx: 10e6, tx: 20e6},
+		{value: "1KiB/2KiB", rx: 1024, tx: 2048},
+		{value: "0/1.5k", rx: 0, tx: 1500},
+		{value: "1500", wantErr: true},
+		{value: "10M/", wantErr: true},
+		{value: "x/20M", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		rx, tx, err := parseRxTx(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseRxTx(%q) = %v, %v, want error", tt.value, rx, tx)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseRxTx(%q) error = %v", tt.value, err)
+			continue
+		}
+		if rx != tt.rx || tx != tt.tx {
+			t.Errorf("parseRxTx(%q) = %v, %v, want %v, %v", tt.value, rx, tx, tt.rx, tt.tx)
+		}
+	}
+}
@@ -2,19 +2,27 @@ package dhcp
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
-	"time"
 
 	"github.com/mikrotik-exporter/collector"
+	"github.com/mikrotik-exporter/config"
+	"github.com/mikrotik-exporter/routeros"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
 // Collector implements the collector.Collector interface for DHCP metrics
 type Collector struct {
-	boundDesc *prometheus.Desc
-	namespace string
+	boundDesc  *prometheus.Desc
+	leasesDesc *prometheus.Desc
+	perLease   bool
+	namespace  string
+}
+
+// Options are the module options of the DHCP collector
+type Options struct {
+	// Leases enables the per-lease series (default: true). Large networks can
+	// disable them and only keep the lease counts per server.
+	Leases *bool `yaml:"leases,omitempty"`
 }
 
 // DHCPLeaseData represents the structure returned by Mikrotik DHCP lease API
@@ -41,6 +49,7 @@ type DHCPLeaseData struct {
 // NewCollector creates a new DHCP collector
 func NewCollector() *Collector {
 	c := &Collector{
+		perLease:  true,
 		namespace: "mikrotik_exporter", // default namespace
 	}
 	c.initMetrics()
@@ -55,6 +64,12 @@ func (c *Collector) initMetrics() {
 		[]string{"device_ip", "mac", "dhcp_server", "device_hostname"},
 		nil,
 	)
+	c.leasesDesc = prometheus.NewDesc(
+		c.namespace+"_dhcp_leases",
+		"Number of DHCP leases by server and status",
+		[]string{"dhcp_server", "status"},
+		nil,
+	)
 }
 
 // Name returns the collector name
@@ -65,6 +80,7 @@ func (c *Collector) Name() string {
 // Describe sends the descriptors of each metric over to the provided channel
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.boundDesc
+	ch <- c.leasesDesc
 }
 
 // SetNamespace sets the metrics namespace prefix
@@ -73,14 +89,33 @@ func (c *Collector) SetNamespace(namespace string) {
 	c.initMetrics()
 }
 
+// Configure returns a copy of the collector with the per-lease series enabled
+// or disabled
+func (c *Collector) Configure(options config.CollectorOptions) (collector.Collector, error) {
+	var opts Options
+	if err := options.Decode(&opts); err != nil {
+		return nil, err
+	}
+
+	clone := *c
+	if opts.Leases != nil {
+		clone.perLease = *opts.Leases
+	}
+	return &clone, nil
+}
+
 // Collect fetches the metrics from Mikrotik device and sends them to Prometheus
-func (c *Collector) Collect(ctx context.Context, target string, auth collector.AuthInfo, ch chan<- prometheus.Metric) error {
+func (c *Collector) Collect(ctx context.Context, client *routeros.Client, ch chan<- prometheus.Metric) error {
 	// Fetch DHCP lease data from Mikrotik REST API
-	leases, err := c.fetchDHCPLeases(ctx, target, auth)
+	leases, err := c.fetchDHCPLeases(ctx, client)
 	if err != nil {
 		return fmt.Errorf("failed to fetch DHCP leases: %w", err)
 	}
 
+	// Lease counts by server and status
+	type leaseKey struct{ server, status string }
+	counts := make(map[leaseKey]int)
+
 	// Process each DHCP lease
 	for _, lease := range leases {
 		// Use active fields if available, fallback to regular fields
@@ -109,6 +144,11 @@ func (c *Collector) Collect(ctx context.Context, target string, auth collector.A
 			continue
 		}
 
+		counts[leaseKey{dhcpServer, lease.Status}]++
+		if !c.perLease {
+			continue
+		}
+
 		// Create labels for this lease
 		labels := []string{
 			ip,
@@ -125,37 +165,17 @@ func (c *Collector) Collect(ctx context.Context, target string, auth collector.A
 		ch <- prometheus.MustNewConstMetric(c.boundDesc, prometheus.GaugeValue, boundValue, labels...)
 	}
 
+	for key, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.leasesDesc, prometheus.GaugeValue, float64(count), key.server, key.status)
+	}
+
 	return nil
 }
 
 // fetchDHCPLeases fetches DHCP lease data from Mikrotik REST API
-func (c *Collector) fetchDHCPLeases(ctx context.Context, target string, auth collector.AuthInfo) ([]DHCPLeaseData, error) {
-	url := fmt.Sprintf("http://%s/rest/ip/dhcp-server/lease", target)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.SetBasicAuth(auth.Username, auth.Password)
-	req.Header.Set("Accept", "application/json")
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
-	}
-
+func (c *Collector) fetchDHCPLeases(ctx context.Context, client *routeros.Client) ([]DHCPLeaseData, error) {
 	var leases []DHCPLeaseData
-	if err := json.NewDecoder(resp.Body).Decode(&leases); err != nil {
+	if err := client.Get(ctx, "/ip/dhcp-server/lease", &leases); err != nil {
 		return nil, err
 	}
 
@@ -2,13 +2,13 @@ package firewall
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
+	"slices"
 	"strconv"
-	"time"
 
 	"github.com/mikrotik-exporter/collector"
+	"github.com/mikrotik-exporter/config"
+	"github.com/mikrotik-exporter/routeros"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -18,9 +18,19 @@ type Collector struct {
 	ruleBytesDesc   *prometheus.Desc
 	rulePacketsDesc *prometheus.Desc
 	ruleInfoDesc    *prometheus.Desc
+	tables          []string
 	namespace       string
 }
 
+// tables are the firewall tables queried by default
+var tables = []string{"filter", "nat", "mangle", "raw"}
+
+// Options are the module options of the firewall collector
+type Options struct {
+	// Tables limits the firewall tables to query
+	Tables []string `yaml:"tables,omitempty"`
+}
+
 // FirewallRuleData represents the structure returned by Mikrotik firewall API
 type FirewallRuleData struct {
 	ID                 string `json:".id"`
@@ -40,6 +50,7 @@ type FirewallRuleData struct {
 // NewCollector creates a new firewall collector
 func NewCollector() *Collector {
 	c := &Collector{
+		tables:    tables,
 		namespace: "mikrotik_exporter", // default namespace
 	}
 	c.initMetrics()
@@ -93,13 +104,36 @@ func (c *Collector) SetNamespace(namespace string) {
 	c.initMetrics()
 }
 
-// Collect fetches the metrics from Mikrotik device and sends them to Prometheus
-func (c *Collector) Collect(ctx context.Context, target string, auth collector.AuthInfo, ch chan<- prometheus.Metric) error {
-	// List of firewall tables to query
-	tables := []string{"filter", "nat", "mangle", "raw"}
+// Configure returns a copy of the collector that queries the configured tables
+func (c *Collector) Configure(options config.CollectorOptions) (collector.Collector, error) {
+	var opts Options
+	if err := options.Decode(&opts); err != nil {
+		return nil, err
+	}
+
+	clone := *c
+	if opts.Tables != nil {
+		if len(opts.Tables) == 0 {
+			return nil, fmt.Errorf("no tables configured")
+		}
+		for i, table := range opts.Tables {
+			if !slices.Contains(tables, table) {
+				return nil, fmt.Errorf("unknown table '%s' (expected filter, nat, mangle or raw)", table)
+			}
+			// The rules of a table would be reported twice
+			if slices.Contains(opts.Tables[:i], table) {
+				return nil, fmt.Errorf("table '%s' is listed more than once", table)
+			}
+		}
+		clone.tables = opts.Tables
+	}
+	return &clone, nil
+}
 
-	for _, table := range tables {
-		rules, err := c.fetchFirewallRules(ctx, target, auth, table)
+// Collect fetches the metrics from Mikrotik device and sends them to Prometheus
+func (c *Collector) Collect(ctx context.Context, client *routeros.Client, ch chan<- prometheus.Metric) error {
+	for _, table := range c.tables {
+		rules, err := c.fetchFirewallRules(ctx, client, table)
 		if err != nil {
 			return fmt.Errorf("failed to fetch %s rules: %w", table, err)
 		}
@@ -123,11 +157,15 @@ func (c *Collector) Collect(ctx context.Context, target string, auth collector.A
 			// Rule bytes
 			if bytes, err := c.parseNumericField(rule.Bytes); err == nil {
 				ch <- prometheus.MustNewConstMetric(c.ruleBytesDesc, prometheus.CounterValue, bytes, labels...)
+			} else {
+				client.ParseError("bytes", rule.Bytes, err)
 			}
 
 			// Rule packets
 			if packets, err := c.parseNumericField(rule.Packets); err == nil {
 				ch <- prometheus.MustNewConstMetric(c.rulePacketsDesc, prometheus.CounterValue, packets, labels...)
+			} else {
+				client.ParseError("packets", rule.Packets, err)
 			}
 
 			// Rule info
@@ -150,33 +188,9 @@ func (c *Collector) Collect(ctx context.Context, target string, auth collector.A
 }
 
 // fetchFirewallRules fetches firewall rule data from Mikrotik REST API
-func (c *Collector) fetchFirewallRules(ctx context.Context, target string, auth collector.AuthInfo, table string) ([]FirewallRuleData, error) {
-	url := fmt.Sprintf("http://%s/rest/ip/firewall/%s", target, table)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.SetBasicAuth(auth.Username, auth.Password)
-	req.Header.Set("Accept", "application/json")
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
-	}
-
+func (c *Collector) fetchFirewallRules(ctx context.Context, client *routeros.Client, table string) ([]FirewallRuleData, error) {
 	var rules []FirewallRuleData
-	if err := json.NewDecoder(resp.Body).Decode(&rules); err != nil {
+	if err := client.Get(ctx, "/ip/firewall/"+table, &rules); err != nil {
 		return nil, err
 	}
 
@@ -2,13 +2,14 @@ package interfaces
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"net/http"
+	"regexp"
 	"strconv"
 	"time"
 
 	"github.com/mikrotik-exporter/collector"
+	"github.com/mikrotik-exporter/config"
+	"github.com/mikrotik-exporter/routeros"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -37,6 +38,10 @@ type Collector struct {
 	lastLinkUpDesc   *prometheus.Desc
 	lastLinkDownDesc *prometheus.Desc
 
+	// Module options
+	include *regexp.Regexp
+	exclude *regexp.Regexp
+
 	namespace string
 }
 
@@ -68,6 +73,14 @@ type InterfaceData struct {
 	Type             string `json:"type"`
 }
 
+// Options are the module options of the interfaces collector
+type Options struct {
+	// Include and Exclude are regular expressions matching whole interface
+	// names; interfaces must match Include and must not match Exclude
+	Include string `yaml:"include,omitempty"`
+	Exclude string `yaml:"exclude,omitempty"`
+}
+
 // NewCollector creates a new interfaces collector
 func NewCollector() *Collector {
 	c := &Collector{
@@ -196,16 +209,41 @@ func (c *Collector) SetNamespace(namespace string) {
 	c.initMetrics()
 }
 
+// Configure returns a copy of the collector that filters interfaces by name
+func (c *Collector) Configure(options config.CollectorOptions) (collector.Collector, error) {
+	var opts Options
+	if err := options.Decode(&opts); err != nil {
+		return nil, err
+	}
+
+	clone := *c
+	var err error
+	if clone.include, err = config.CompileAnchored(opts.Include); err != nil {
+		return nil, fmt.Errorf("invalid include pattern: %w", err)
+	}
+	if clone.exclude, err = config.CompileAnchored(opts.Exclude); err != nil {
+		return nil, fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+	return &clone, nil
+}
+
 // Collect fetches the metrics from Mikrotik device and sends them to Prometheus
-func (c *Collector) Collect(ctx context.Context, target string, auth collector.AuthInfo, ch chan<- prometheus.Metric) error {
+func (c *Collector) Collect(ctx context.Context, client *routeros.Client, ch chan<- prometheus.Metric) error {
 	// Fetch interface data from Mikrotik REST API
-	interfaces, err := c.fetchInterfaces(ctx, target, auth)
+	interfaces, err := c.fetchInterfaces(ctx, client)
 	if err != nil {
 		return fmt.Errorf("failed to fetch interfaces: %w", err)
 	}
 
 	// Process each interface
 	for _, iface := range interfaces {
+		if c.include != nil && !c.include.MatchString(iface.Name) {
+			continue
+		}
+		if c.exclude != nil && c.exclude.MatchString(iface.Name) {
+			continue
+		}
+
 		comment := iface.Comment
 		if comment == "" {
 			comment = ""
@@ -229,40 +267,62 @@ func (c *Collector) Collect(ctx context.Context, target string, auth collector.A
 		// RX metrics
 		if rxBytes, err := parseUint64(iface.RxByte); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.rxBytesDesc, prometheus.CounterValue, float64(rxBytes), basicLabels...)
+		} else {
+			client.ParseError("rx-byte", iface.RxByte, err)
 		}
 		if rxPackets, err := parseUint64(iface.RxPacket); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.rxPacketsDesc, prometheus.CounterValue, float64(rxPackets), basicLabels...)
+		} else {
+			client.ParseError("rx-packet", iface.RxPacket, err)
 		}
 		if fpRxBytes, err := parseUint64(iface.FpRxByte); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.fpRxBytesDesc, prometheus.CounterValue, float64(fpRxBytes), basicLabels...)
+		} else {
+			client.ParseError("fp-rx-byte", iface.FpRxByte, err)
 		}
 		if fpRxPackets, err := parseUint64(iface.FpRxPacket); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.fpRxPacketsDesc, prometheus.CounterValue, float64(fpRxPackets), basicLabels...)
+		} else {
+			client.ParseError("fp-rx-packet", iface.FpRxPacket, err)
 		}
 
 		// TX metrics
 		if txBytes, err := parseUint64(iface.TxByte); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.txBytesDesc, prometheus.CounterValue, float64(txBytes), basicLabels...)
+		} else {
+			client.ParseError("tx-byte", iface.TxByte, err)
 		}
 		if txPackets, err := parseUint64(iface.TxPacket); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.txPacketsDesc, prometheus.CounterValue, float64(txPackets), basicLabels...)
+		} else {
+			client.ParseError("tx-packet", iface.TxPacket, err)
 		}
 		if fpTxBytes, err := parseUint64(iface.FpTxByte); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.fpTxBytesDesc, prometheus.CounterValue, float64(fpTxBytes), basicLabels...)
+		} else {
+			client.ParseError("fp-tx-byte", iface.FpTxByte, err)
 		}
 		if fpTxPackets, err := parseUint64(iface.FpTxPacket); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.fpTxPacketsDesc, prometheus.CounterValue, float64(fpTxPackets), basicLabels...)
+		} else {
+			client.ParseError("fp-tx-packet", iface.FpTxPacket, err)
 		}
 		if txQueueDrop, err := parseUint64(iface.TxQueueDrop); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.txQueueDropDesc, prometheus.CounterValue, float64(txQueueDrop), basicLabels...)
+		} else {
+			client.ParseError("tx-queue-drop", iface.TxQueueDrop, err)
 		}
 
 		// Interface properties
 		if mtu, err := parseUint64(iface.MTU); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.mtuDesc, prometheus.GaugeValue, float64(mtu), basicLabels...)
+		} else {
+			client.ParseError("mtu", iface.MTU, err)
 		}
 		if linkDowns, err := parseUint64(iface.LinkDowns); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.linkDownsDesc, prometheus.CounterValue, float64(linkDowns), basicLabels...)
+		} else {
+			client.ParseError("link-downs", iface.LinkDowns, err)
 		}
 
 		// Last link up/down times
@@ -278,33 +338,9 @@ func (c *Collector) Collect(ctx context.Context, target string, auth collector.A
 }
 
 // fetchInterfaces fetches interface data from Mikrotik REST API
-func (c *Collector) fetchInterfaces(ctx context.Context, target string, auth collector.AuthInfo) ([]InterfaceData, error) {
-	url := fmt.Sprintf("http://%s/rest/interface", target)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.SetBasicAuth(auth.Username, auth.Password)
-	req.Header.Set("Accept", "application/json")
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
-	}
-
+func (c *Collector) fetchInterfaces(ctx context.Context, client *routeros.Client) ([]InterfaceData, error) {
 	var interfaces []InterfaceData
-	if err := json.NewDecoder(resp.Body).Decode(&interfaces); err != nil {
+	if err := client.Get(ctx, "/interface", &interfaces); err != nil {
 		return nil, err
 	}
 
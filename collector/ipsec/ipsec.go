@@ -0,0 +1,343 @@
+package ipsec
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/mikrotik-exporter/collector"
+	"github.com/mikrotik-exporter/config"
+	"github.com/mikrotik-exporter/routeros"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector implements the collector.Collector interface for IPsec metrics
+type Collector struct {
+	peerEstablishedDesc        *prometheus.Desc
+	peerUptimeDesc             *prometheus.Desc
+	peerPhase2SAsDesc          *prometheus.Desc
+	saBytesDesc                *prometheus.Desc
+	saPacketsDesc              *prometheus.Desc
+	saLifetimeDesc             *prometheus.Desc
+	policyEstablishedDesc      *prometheus.Desc
+	policiesNotEstablishedDesc *prometheus.Desc
+	namespace                  string
+}
+
+// ActivePeerData represents the structure returned by the Mikrotik IPsec active peers API
+type ActivePeerData struct {
+	ID            string `json:".id"`
+	LocalAddress  string `json:"local-address"`
+	RemoteAddress string `json:"remote-address"`
+	State         string `json:"state"`
+	Side          string `json:"side"`
+	Uptime        string `json:"uptime"`
+	Ph2Total      string `json:"ph2-total"`
+}
+
+// InstalledSAData represents the structure returned by the Mikrotik IPsec installed SA API.
+// CurrentPackets is only reported by RouterOS 7.
+type InstalledSAData struct {
+	ID             string `json:".id"`
+	SPI            string `json:"spi"`
+	SrcAddress     string `json:"src-address"`
+	DstAddress     string `json:"dst-address"`
+	State          string `json:"state"`
+	CurrentBytes   string `json:"current-bytes"`
+	CurrentPackets string `json:"current-packets"`
+	ExpiresIn      string `json:"expires-in"`
+}
+
+// PolicyData represents the structure returned by the Mikrotik IPsec policy API
+type PolicyData struct {
+	ID           string `json:".id"`
+	Peer         string `json:"peer"`
+	SrcAddress   string `json:"src-address"`
+	DstAddress   string `json:"dst-address"`
+	SASrcAddress string `json:"sa-src-address"`
+	SADstAddress string `json:"sa-dst-address"`
+	Action       string `json:"action"`
+	Ph2State     string `json:"ph2-state"`
+	Template     string `json:"template"`
+	Disabled     string `json:"disabled"`
+}
+
+// installedSAProperties are the properties needed from the installed SAs,
+// which also hold the encryption and authentication keys
+var installedSAProperties = []string{
+	".id", "spi", "src-address", "dst-address", "state", "current-bytes", "current-packets", "expires-in",
+}
+
+// NewCollector creates a new IPsec collector
+func NewCollector() *Collector {
+	c := &Collector{
+		namespace: "mikrotik_exporter", // default namespace
+	}
+	c.initMetrics()
+	return c
+}
+
+// initMetrics initializes the metric descriptors with the current namespace
+func (c *Collector) initMetrics() {
+	peerLabels := []string{"peer", "remote_address"}
+	saLabels := []string{"peer", "remote_address", "spi", "direction"}
+
+	c.peerEstablishedDesc = prometheus.NewDesc(
+		c.namespace+"_ipsec_peer_established",
+		"IPsec active peer status (1 = established, 0 = not established)",
+		peerLabels,
+		nil,
+	)
+	c.peerUptimeDesc = prometheus.NewDesc(
+		c.namespace+"_ipsec_peer_uptime_seconds",
+		"IPsec active peer uptime in seconds",
+		peerLabels,
+		nil,
+	)
+	c.peerPhase2SAsDesc = prometheus.NewDesc(
+		c.namespace+"_ipsec_peer_phase2_sas",
+		"Number of phase 2 SAs of the IPsec active peer",
+		peerLabels,
+		nil,
+	)
+	c.saBytesDesc = prometheus.NewDesc(
+		c.namespace+"_ipsec_sa_bytes_total",
+		"Number of bytes processed by the installed IPsec SA",
+		saLabels,
+		nil,
+	)
+	c.saPacketsDesc = prometheus.NewDesc(
+		c.namespace+"_ipsec_sa_packets_total",
+		"Number of packets processed by the installed IPsec SA",
+		saLabels,
+		nil,
+	)
+	c.saLifetimeDesc = prometheus.NewDesc(
+		c.namespace+"_ipsec_sa_lifetime_remaining_seconds",
+		"Time until the installed IPsec SA expires in seconds",
+		saLabels,
+		nil,
+	)
+	c.policyEstablishedDesc = prometheus.NewDesc(
+		c.namespace+"_ipsec_policy_established",
+		"IPsec policy phase 2 status (1 = established, 0 = not established)",
+		[]string{"peer", "remote_address", "src_address", "dst_address"},
+		nil,
+	)
+	c.policiesNotEstablishedDesc = prometheus.NewDesc(
+		c.namespace+"_ipsec_policies_not_established",
+		"Number of encrypting IPsec policies whose phase 2 is not established",
+		nil,
+		nil,
+	)
+}
+
+// Name returns the collector name
+func (c *Collector) Name() string {
+	return "ipsec"
+}
+
+// Describe sends the descriptors of each metric over to the provided channel
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.peerEstablishedDesc
+	ch <- c.peerUptimeDesc
+	ch <- c.peerPhase2SAsDesc
+	ch <- c.saBytesDesc
+	ch <- c.saPacketsDesc
+	ch <- c.saLifetimeDesc
+	ch <- c.policyEstablishedDesc
+	ch <- c.policiesNotEstablishedDesc
+}
+
+// SetNamespace sets the metrics namespace prefix
+func (c *Collector) SetNamespace(namespace string) {
+	c.namespace = namespace
+	c.initMetrics()
+}
+
+// Configure returns the collector; it has no options
+func (c *Collector) Configure(options config.CollectorOptions) (collector.Collector, error) {
+	if err := collector.NoOptions(options); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Collect fetches the metrics from Mikrotik device and sends them to Prometheus
+func (c *Collector) Collect(ctx context.Context, client *routeros.Client, ch chan<- prometheus.Metric) error {
+	policies, err := c.fetchPolicies(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to fetch IPsec policies: %w", err)
+	}
+
+	// Active peers and SAs only know addresses; the peer names come from
+	// the policies. Policies that only differ in e.g. protocol are merged
+	// and established only if all of them are.
+	type policyKey struct{ peer, remoteAddress, srcAddress, dstAddress string }
+	var policyKeys []policyKey
+	policyStates := make(map[policyKey]float64)
+	peerNames := make(map[string]string)
+	for _, policy := range policies {
+		if policy.Template == "true" || policy.Disabled == "true" || policy.Action == "none" || policy.Action == "discard" {
+			continue
+		}
+
+		remoteAddress := hostOnly(policy.SADstAddress)
+		if remoteAddress != "" && policy.Peer != "" {
+			peerNames[remoteAddress] = policy.Peer
+		}
+
+		established := 0.0
+		if policy.Ph2State == "established" {
+			established = 1.0
+		}
+
+		key := policyKey{policy.Peer, remoteAddress, policy.SrcAddress, policy.DstAddress}
+		if state, exists := policyStates[key]; exists {
+			policyStates[key] = min(state, established)
+			continue
+		}
+		policyKeys = append(policyKeys, key)
+		policyStates[key] = established
+	}
+	// Counted after merging so that it matches the policy_established series
+	notEstablished := 0
+	for _, key := range policyKeys {
+		ch <- prometheus.MustNewConstMetric(c.policyEstablishedDesc, prometheus.GaugeValue, policyStates[key],
+			key.peer, key.remoteAddress, key.srcAddress, key.dstAddress)
+		if policyStates[key] == 0 {
+			notEstablished++
+		}
+	}
+	ch <- prometheus.MustNewConstMetric(c.policiesNotEstablishedDesc, prometheus.GaugeValue, float64(notEstablished))
+
+	peers, err := c.fetchActivePeers(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to fetch IPsec active peers: %w", err)
+	}
+
+	// Peers behind the same NAT address are merged: established if any of
+	// them is, with the longest uptime and all of their SAs
+	type peerState struct {
+		established, uptime, phase2SAs float64
+		hasUptime, hasPhase2SAs        bool
+	}
+	var remoteAddressOrder []string
+	peerStates := make(map[string]*peerState)
+	for _, peer := range peers {
+		remoteAddress := hostOnly(peer.RemoteAddress)
+		state, exists := peerStates[remoteAddress]
+		if !exists {
+			state = &peerState{}
+			peerStates[remoteAddress] = state
+			remoteAddressOrder = append(remoteAddressOrder, remoteAddress)
+		}
+
+		if peer.State == "established" {
+			state.established = 1.0
+		}
+
+		if uptime, err := routeros.ParseDuration(peer.Uptime); err == nil {
+			state.uptime = max(state.uptime, uptime.Seconds())
+			state.hasUptime = true
+		} else {
+			client.ParseError("uptime", peer.Uptime, err)
+		}
+
+		if sas, err := strconv.ParseFloat(peer.Ph2Total, 64); err == nil {
+			state.phase2SAs += sas
+			state.hasPhase2SAs = true
+		} else {
+			client.ParseError("ph2-total", peer.Ph2Total, err)
+		}
+	}
+
+	remoteAddresses := make(map[string]bool)
+	for _, remoteAddress := range remoteAddressOrder {
+		remoteAddresses[remoteAddress] = true
+		state := peerStates[remoteAddress]
+		labels := []string{peerNames[remoteAddress], remoteAddress}
+
+		ch <- prometheus.MustNewConstMetric(c.peerEstablishedDesc, prometheus.GaugeValue, state.established, labels...)
+		if state.hasUptime {
+			ch <- prometheus.MustNewConstMetric(c.peerUptimeDesc, prometheus.GaugeValue, state.uptime, labels...)
+		}
+		if state.hasPhase2SAs {
+			ch <- prometheus.MustNewConstMetric(c.peerPhase2SAsDesc, prometheus.GaugeValue, state.phase2SAs, labels...)
+		}
+	}
+	for address := range peerNames {
+		remoteAddresses[address] = true
+	}
+
+	sas, err := c.fetchInstalledSAs(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to fetch IPsec installed SAs: %w", err)
+	}
+	for _, sa := range sas {
+		// SAs towards a remote address are outbound, the ones from it inbound
+		remoteAddress, direction := hostOnly(sa.DstAddress), "out"
+		if src := hostOnly(sa.SrcAddress); remoteAddresses[src] {
+			remoteAddress, direction = src, "in"
+		}
+		labels := []string{peerNames[remoteAddress], remoteAddress, sa.SPI, direction}
+
+		if bytes, err := strconv.ParseFloat(sa.CurrentBytes, 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.saBytesDesc, prometheus.CounterValue, bytes, labels...)
+		} else {
+			client.ParseError("current-bytes", sa.CurrentBytes, err)
+		}
+		if packets, err := strconv.ParseFloat(sa.CurrentPackets, 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.saPacketsDesc, prometheus.CounterValue, packets, labels...)
+		} else {
+			client.ParseError("current-packets", sa.CurrentPackets, err)
+		}
+		if expiresIn, err := routeros.ParseDuration(sa.ExpiresIn); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.saLifetimeDesc, prometheus.GaugeValue, expiresIn.Seconds(), labels...)
+		} else {
+			client.ParseError("expires-in", sa.ExpiresIn, err)
+		}
+	}
+
+	return nil
+}
+
+// hostOnly strips the port from addresses such as "192.0.2.1:4500", which
+// RouterOS reports for peers behind NAT
+func hostOnly(address string) string {
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	return address
+}
+
+// fetchPolicies fetches the IPsec policies
+func (c *Collector) fetchPolicies(ctx context.Context, client *routeros.Client) ([]PolicyData, error) {
+	var policies []PolicyData
+	if err := client.Get(ctx, "/ip/ipsec/policy", &policies); err != nil {
+		return nil, err
+	}
+
+	return policies, nil
+}
+
+// fetchActivePeers fetches the IPsec active peers
+func (c *Collector) fetchActivePeers(ctx context.Context, client *routeros.Client) ([]ActivePeerData, error) {
+	var peers []ActivePeerData
+	if err := client.Get(ctx, "/ip/ipsec/active-peers", &peers); err != nil {
+		return nil, err
+	}
+
+	return peers, nil
+}
+
+// fetchInstalledSAs fetches the IPsec installed SAs without their keys
+func (c *Collector) fetchInstalledSAs(ctx context.Context, client *routeros.Client) ([]InstalledSAData, error) {
+	var sas []InstalledSAData
+	if err := client.Get(ctx, "/ip/ipsec/installed-sa", &sas, routeros.Proplist(installedSAProperties...)); err != nil {
+		return nil, err
+	}
+
+	return sas, nil
+}
@@ -0,0 +1,273 @@
+package ospf
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mikrotik-exporter/collector"
+	"github.com/mikrotik-exporter/config"
+	"github.com/mikrotik-exporter/routeros"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector implements the collector.Collector interface for OSPF metrics
+type Collector struct {
+	neighborStateDesc        *prometheus.Desc
+	neighborAdjacencyDesc    *prometheus.Desc
+	neighborStateChangesDesc *prometheus.Desc
+	instanceInfoDesc         *prometheus.Desc
+	instanceEnabledDesc      *prometheus.Desc
+	areaInfoDesc             *prometheus.Desc
+	areaLSAsDesc             *prometheus.Desc
+	namespace                string
+}
+
+// OSPFNeighborData represents the structure returned by the Mikrotik OSPF neighbor API.
+// Area is only reported by RouterOS 7 and Interface only by RouterOS 6.
+type OSPFNeighborData struct {
+	ID           string `json:".id"`
+	Instance     string `json:"instance"`
+	Area         string `json:"area"`
+	Address      string `json:"address"`
+	Interface    string `json:"interface"`
+	RouterID     string `json:"router-id"`
+	Priority     string `json:"priority"`
+	State        string `json:"state"`
+	StateChanges string `json:"state-changes"`
+	Adjacency    string `json:"adjacency"`
+}
+
+// OSPFInstanceData represents the structure returned by the Mikrotik OSPF instance API
+type OSPFInstanceData struct {
+	ID       string `json:".id"`
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	VRF      string `json:"vrf"`
+	RouterID string `json:"router-id"`
+	Disabled string `json:"disabled"`
+}
+
+// OSPFAreaData represents the structure returned by the Mikrotik OSPF area API
+type OSPFAreaData struct {
+	ID       string `json:".id"`
+	Name     string `json:"name"`
+	Instance string `json:"instance"`
+	AreaID   string `json:"area-id"`
+	Type     string `json:"type"`
+	Disabled string `json:"disabled"`
+}
+
+// OSPFLSAData represents the structure returned by the Mikrotik OSPF LSA API
+type OSPFLSAData struct {
+	Instance string `json:"instance"`
+	Area     string `json:"area"`
+	Type     string `json:"type"`
+}
+
+// neighborStates maps the neighbor states to their numeric value, in the
+// order of RFC 2328; unknown states are reported as 0
+var neighborStates = map[string]float64{
+	"down":     1,
+	"attempt":  2,
+	"init":     3,
+	"2-way":    4,
+	"exstart":  5,
+	"exchange": 6,
+	"loading":  7,
+	"full":     8,
+}
+
+// NewCollector creates a new OSPF collector
+func NewCollector() *Collector {
+	c := &Collector{
+		namespace: "mikrotik_exporter", // default namespace
+	}
+	c.initMetrics()
+	return c
+}
+
+// initMetrics initializes the metric descriptors with the current namespace
+func (c *Collector) initMetrics() {
+	neighborLabels := []string{"ospf_instance", "area", "router_id", "address", "interface"}
+
+	c.neighborStateDesc = prometheus.NewDesc(
+		c.namespace+"_ospf_neighbor_state",
+		"OSPF neighbor state (1 = Down, 2 = Attempt, 3 = Init, 4 = 2-Way, 5 = ExStart, 6 = Exchange, 7 = Loading, 8 = Full, 0 = unknown)",
+		neighborLabels,
+		nil,
+	)
+	c.neighborAdjacencyDesc = prometheus.NewDesc(
+		c.namespace+"_ospf_neighbor_adjacency_uptime_seconds",
+		"Time since the OSPF adjacency was formed in seconds",
+		neighborLabels,
+		nil,
+	)
+	c.neighborStateChangesDesc = prometheus.NewDesc(
+		c.namespace+"_ospf_neighbor_state_changes_total",
+		"Number of OSPF neighbor state changes",
+		neighborLabels,
+		nil,
+	)
+	c.instanceInfoDesc = prometheus.NewDesc(
+		c.namespace+"_ospf_instance_info",
+		"OSPF instance information",
+		[]string{"ospf_instance", "router_id", "version", "vrf"},
+		nil,
+	)
+	c.instanceEnabledDesc = prometheus.NewDesc(
+		c.namespace+"_ospf_instance_enabled",
+		"OSPF instance enabled status (1 = enabled, 0 = disabled)",
+		[]string{"ospf_instance"},
+		nil,
+	)
+	c.areaInfoDesc = prometheus.NewDesc(
+		c.namespace+"_ospf_area_info",
+		"OSPF area information",
+		[]string{"ospf_instance", "area", "area_id", "type"},
+		nil,
+	)
+	c.areaLSAsDesc = prometheus.NewDesc(
+		c.namespace+"_ospf_area_lsas",
+		"Number of LSAs in the OSPF database by area and LSA type",
+		[]string{"ospf_instance", "area", "type"},
+		nil,
+	)
+}
+
+// Name returns the collector name
+func (c *Collector) Name() string {
+	return "ospf"
+}
+
+// Describe sends the descriptors of each metric over to the provided channel
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.neighborStateDesc
+	ch <- c.neighborAdjacencyDesc
+	ch <- c.neighborStateChangesDesc
+	ch <- c.instanceInfoDesc
+	ch <- c.instanceEnabledDesc
+	ch <- c.areaInfoDesc
+	ch <- c.areaLSAsDesc
+}
+
+// SetNamespace sets the metrics namespace prefix
+func (c *Collector) SetNamespace(namespace string) {
+	c.namespace = namespace
+	c.initMetrics()
+}
+
+// Configure returns the collector; it has no options
+func (c *Collector) Configure(options config.CollectorOptions) (collector.Collector, error) {
+	if err := collector.NoOptions(options); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Collect fetches the metrics from Mikrotik device and sends them to Prometheus
+func (c *Collector) Collect(ctx context.Context, client *routeros.Client, ch chan<- prometheus.Metric) error {
+	instances, err := c.fetchInstances(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to fetch OSPF instances: %w", err)
+	}
+	for _, instance := range instances {
+		ch <- prometheus.MustNewConstMetric(c.instanceInfoDesc, prometheus.GaugeValue, 1,
+			instance.Name, instance.RouterID, instance.Version, instance.VRF)
+
+		enabled := 1.0
+		if instance.Disabled == "true" {
+			enabled = 0.0
+		}
+		ch <- prometheus.MustNewConstMetric(c.instanceEnabledDesc, prometheus.GaugeValue, enabled, instance.Name)
+	}
+
+	areas, err := c.fetchAreas(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to fetch OSPF areas: %w", err)
+	}
+	for _, area := range areas {
+		ch <- prometheus.MustNewConstMetric(c.areaInfoDesc, prometheus.GaugeValue, 1,
+			area.Instance, area.Name, area.AreaID, area.Type)
+	}
+
+	neighbors, err := c.fetchNeighbors(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to fetch OSPF neighbors: %w", err)
+	}
+	for _, neighbor := range neighbors {
+		labels := []string{neighbor.Instance, neighbor.Area, neighbor.RouterID, neighbor.Address, neighbor.Interface}
+
+		state := neighborStates[strings.ToLower(neighbor.State)]
+		ch <- prometheus.MustNewConstMetric(c.neighborStateDesc, prometheus.GaugeValue, state, labels...)
+
+		if changes, err := strconv.ParseFloat(neighbor.StateChanges, 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.neighborStateChangesDesc, prometheus.CounterValue, changes, labels...)
+		} else {
+			client.ParseError("state-changes", neighbor.StateChanges, err)
+		}
+
+		if adjacency, err := routeros.ParseDuration(neighbor.Adjacency); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.neighborAdjacencyDesc, prometheus.GaugeValue, adjacency.Seconds(), labels...)
+		} else {
+			client.ParseError("adjacency", neighbor.Adjacency, err)
+		}
+	}
+
+	lsas, err := c.fetchLSAs(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to fetch OSPF LSAs: %w", err)
+	}
+	type lsaKey struct{ instance, area, lsaType string }
+	counts := make(map[lsaKey]int)
+	for _, lsa := range lsas {
+		counts[lsaKey{lsa.Instance, lsa.Area, lsa.Type}]++
+	}
+	for key, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.areaLSAsDesc, prometheus.GaugeValue, float64(count), key.instance, key.area, key.lsaType)
+	}
+
+	return nil
+}
+
+// fetchInstances fetches the OSPF instances
+func (c *Collector) fetchInstances(ctx context.Context, client *routeros.Client) ([]OSPFInstanceData, error) {
+	var instances []OSPFInstanceData
+	if err := client.Get(ctx, "/routing/ospf/instance", &instances); err != nil {
+		return nil, err
+	}
+
+	return instances, nil
+}
+
+// fetchAreas fetches the OSPF areas
+func (c *Collector) fetchAreas(ctx context.Context, client *routeros.Client) ([]OSPFAreaData, error) {
+	var areas []OSPFAreaData
+	if err := client.Get(ctx, "/routing/ospf/area", &areas); err != nil {
+		return nil, err
+	}
+
+	return areas, nil
+}
+
+// fetchNeighbors fetches the OSPF neighbors
+func (c *Collector) fetchNeighbors(ctx context.Context, client *routeros.Client) ([]OSPFNeighborData, error) {
+	var neighbors []OSPFNeighborData
+	if err := client.Get(ctx, "/routing/ospf/neighbor", &neighbors); err != nil {
+		return nil, err
+	}
+
+	return neighbors, nil
+}
+
+// fetchLSAs fetches the OSPF link-state database, which can be large, so
+// only the properties needed for counting are requested
+func (c *Collector) fetchLSAs(ctx context.Context, client *routeros.Client) ([]OSPFLSAData, error) {
+	var lsas []OSPFLSAData
+	if err := client.Get(ctx, "/routing/ospf/lsa", &lsas, routeros.Proplist("instance", "area", "type")); err != nil {
+		return nil, err
+	}
+
+	return lsas, nil
+}
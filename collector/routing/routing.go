@@ -0,0 +1,237 @@
+package routing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+
+	"github.com/mikrotik-exporter/collector"
+	"github.com/mikrotik-exporter/config"
+	"github.com/mikrotik-exporter/routeros"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Collector implements the collector.Collector interface for routing table metrics
+type Collector struct {
+	routesDesc        *prometheus.Desc
+	watchedPrefixDesc *prometheus.Desc
+
+	// watched are the prefixes reported by watchedPrefixDesc
+	watched []*net.IPNet
+
+	namespace string
+}
+
+// RouteData represents the structure returned by the Mikrotik route API. The
+// protocol of a route is reported as a set of boolean flags.
+type RouteData struct {
+	DstAddress   string `json:"dst-address"`
+	Gateway      string `json:"gateway"`
+	RoutingTable string `json:"routing-table"`
+	RoutingMark  string `json:"routing-mark"` // RouterOS 6
+	Active       string `json:"active"`
+	Connect      string `json:"connect"`
+	Static       string `json:"static"`
+	BGP          string `json:"bgp"`
+	OSPF         string `json:"ospf"`
+	DHCP         string `json:"dhcp"`
+	RIP          string `json:"rip"`
+}
+
+// Options are the module options of the routing collector
+type Options struct {
+	// WatchedPrefixes are reported with their gateway, or as missing
+	WatchedPrefixes []string `yaml:"watched_prefixes,omitempty"`
+}
+
+// families maps the address families to their route menus
+var families = []struct {
+	name string
+	path string
+}{
+	{"ipv4", "/ip/route"},
+	{"ipv6", "/ipv6/route"},
+}
+
+// routeProperties are the properties needed from the route menus, which can
+// hold a full BGP table
+var routeProperties = []string{
+	"dst-address", "gateway", "routing-table", "routing-mark", "active",
+	"connect", "static", "bgp", "ospf", "dhcp", "rip",
+}
+
+// NewCollector creates a new routing collector
+func NewCollector() *Collector {
+	c := &Collector{
+		namespace: "mikrotik_exporter", // default namespace
+	}
+	c.initMetrics()
+	return c
+}
+
+// initMetrics initializes the metric descriptors with the current namespace
+func (c *Collector) initMetrics() {
+	c.routesDesc = prometheus.NewDesc(
+		c.namespace+"_routing_routes",
+		"Number of routes by routing table, address family, protocol and state (active = true/false)",
+		[]string{"table", "address_family", "protocol", "active"},
+		nil,
+	)
+	c.watchedPrefixDesc = prometheus.NewDesc(
+		c.namespace+"_routing_watched_prefix",
+		"Watched prefix status (1 = active route, 0 = inactive or missing); missing prefixes have empty table and gateway",
+		[]string{"prefix", "table", "gateway"},
+		nil,
+	)
+}
+
+// Name returns the collector name
+func (c *Collector) Name() string {
+	return "routing"
+}
+
+// Describe sends the descriptors of each metric over to the provided channel
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.routesDesc
+	ch <- c.watchedPrefixDesc
+}
+
+// SetNamespace sets the metrics namespace prefix
+func (c *Collector) SetNamespace(namespace string) {
+	c.namespace = namespace
+	c.initMetrics()
+}
+
+// Configure returns a copy of the collector that reports the watched prefixes
+func (c *Collector) Configure(options config.CollectorOptions) (collector.Collector, error) {
+	var opts Options
+	if err := options.Decode(&opts); err != nil {
+		return nil, err
+	}
+
+	clone := *c
+	clone.watched = nil
+	for _, prefix := range opts.WatchedPrefixes {
+		_, network, err := net.ParseCIDR(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid watched prefix '%s'", prefix)
+		}
+		if slices.ContainsFunc(clone.watched, func(n *net.IPNet) bool { return n.String() == network.String() }) {
+			continue
+		}
+		clone.watched = append(clone.watched, network)
+	}
+	return &clone, nil
+}
+
+// Collect fetches the metrics from Mikrotik device and sends them to Prometheus
+func (c *Collector) Collect(ctx context.Context, client *routeros.Client, ch chan<- prometheus.Metric) error {
+	type routeKey struct {
+		table, family, protocol, active string
+	}
+	counts := make(map[routeKey]int)
+
+	// Routes to a watched prefix by table and gateway; a prefix with an
+	// active and an inactive route through the same gateway is active
+	type watchedKey struct {
+		prefix, table, gateway string
+	}
+	watchedRoutes := make(map[watchedKey]float64)
+	found := make([]bool, len(c.watched))
+
+	for _, family := range families {
+		routes, err := c.fetchRoutes(ctx, client, family.path)
+		if err != nil {
+			// IPv6 is an optional package on RouterOS 6
+			if family.name == "ipv6" && errors.Is(err, routeros.ErrNotFound) {
+				continue
+			}
+			return fmt.Errorf("failed to fetch %s routes: %w", family.name, err)
+		}
+
+		for _, route := range routes {
+			table := route.RoutingTable
+			if table == "" {
+				table = route.RoutingMark
+			}
+			if table == "" {
+				table = "main"
+			}
+
+			active := "false"
+			if route.Active == "true" {
+				active = "true"
+			}
+			counts[routeKey{table, family.name, protocol(route), active}]++
+
+			if len(c.watched) == 0 {
+				continue
+			}
+			_, dst, err := net.ParseCIDR(route.DstAddress)
+			if err != nil {
+				client.ParseError("dst-address", route.DstAddress, err)
+				continue
+			}
+			for i, watched := range c.watched {
+				if dst.String() != watched.String() {
+					continue
+				}
+				found[i] = true
+
+				key := watchedKey{watched.String(), table, route.Gateway}
+				if active == "true" {
+					watchedRoutes[key] = 1
+				} else if _, exists := watchedRoutes[key]; !exists {
+					watchedRoutes[key] = 0
+				}
+			}
+		}
+	}
+
+	for key, count := range counts {
+		ch <- prometheus.MustNewConstMetric(c.routesDesc, prometheus.GaugeValue, float64(count), key.table, key.family, key.protocol, key.active)
+	}
+
+	for key, value := range watchedRoutes {
+		ch <- prometheus.MustNewConstMetric(c.watchedPrefixDesc, prometheus.GaugeValue, value, key.prefix, key.table, key.gateway)
+	}
+	for i, watched := range c.watched {
+		if !found[i] {
+			ch <- prometheus.MustNewConstMetric(c.watchedPrefixDesc, prometheus.GaugeValue, 0, watched.String(), "", "")
+		}
+	}
+
+	return nil
+}
+
+// protocol returns the protocol that installed a route
+func protocol(route RouteData) string {
+	switch {
+	case route.Connect == "true":
+		return "connected"
+	case route.Static == "true":
+		return "static"
+	case route.BGP == "true":
+		return "bgp"
+	case route.OSPF == "true":
+		return "ospf"
+	case route.DHCP == "true":
+		return "dhcp"
+	case route.RIP == "true":
+		return "rip"
+	default:
+		return "other"
+	}
+}
+
+// fetchRoutes fetches the routes of an address family
+func (c *Collector) fetchRoutes(ctx context.Context, client *routeros.Client, path string) ([]RouteData, error) {
+	var routes []RouteData
+	if err := client.Get(ctx, path, &routes, routeros.Proplist(routeProperties...)); err != nil {
+		return nil, err
+	}
+
+	return routes, nil
+}
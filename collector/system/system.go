@@ -2,15 +2,12 @@ package system
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"log"
-	"net/http"
-	"regexp"
 	"strconv"
-	"time"
 
 	"github.com/mikrotik-exporter/collector"
+	"github.com/mikrotik-exporter/config"
+	"github.com/mikrotik-exporter/routeros"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -180,10 +177,18 @@ func (c *Collector) SetNamespace(namespace string) {
 	c.initMetrics()
 }
 
+// Configure returns the collector; it has no options
+func (c *Collector) Configure(options config.CollectorOptions) (collector.Collector, error) {
+	if err := collector.NoOptions(options); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
 // Collect fetches the metrics from Mikrotik device and sends them to Prometheus
-func (c *Collector) Collect(ctx context.Context, target string, auth collector.AuthInfo, ch chan<- prometheus.Metric) error {
+func (c *Collector) Collect(ctx context.Context, client *routeros.Client, ch chan<- prometheus.Metric) error {
 	// Fetch system resource data from Mikrotik REST API
-	resource, err := c.fetchSystemResource(ctx, target, auth)
+	resource, err := c.fetchSystemResource(ctx, client)
 	if err != nil {
 		return fmt.Errorf("failed to fetch system resource: %w", err)
 	}
@@ -199,46 +204,67 @@ func (c *Collector) Collect(ctx context.Context, target string, auth collector.A
 	// CPU metrics
 	if cpuCores, err := parseUint64(resource.CPUCount); err == nil {
 		ch <- prometheus.MustNewConstMetric(c.cpuCoresDesc, prometheus.GaugeValue, float64(cpuCores))
+	} else {
+		client.ParseError("cpu-count", resource.CPUCount, err)
 	}
 	if cpuFreq, err := parseUint64(resource.CPUFrequency); err == nil {
 		ch <- prometheus.MustNewConstMetric(c.cpuFreqDesc, prometheus.GaugeValue, float64(cpuFreq))
+	} else {
+		client.ParseError("cpu-frequency", resource.CPUFrequency, err)
 	}
 	if cpuLoad, err := parseUint64(resource.CPULoad); err == nil {
 		ch <- prometheus.MustNewConstMetric(c.cpuLoadDesc, prometheus.GaugeValue, float64(cpuLoad))
+	} else {
+		client.ParseError("cpu-load", resource.CPULoad, err)
 	}
 
 	// Disk metrics
 	if totalDisk, err := parseUint64(resource.TotalHDDSpace); err == nil {
 		ch <- prometheus.MustNewConstMetric(c.totalDiskDesc, prometheus.GaugeValue, float64(totalDisk))
+	} else {
+		client.ParseError("total-hdd-space", resource.TotalHDDSpace, err)
 	}
 	if freeDisk, err := parseUint64(resource.FreeHDDSpace); err == nil {
 		ch <- prometheus.MustNewConstMetric(c.freeDiskDesc, prometheus.GaugeValue, float64(freeDisk))
+	} else {
+		client.ParseError("free-hdd-space", resource.FreeHDDSpace, err)
 	}
 	if badBlocks, err := parseUint64(resource.BadBlocks); err == nil {
 		ch <- prometheus.MustNewConstMetric(c.badBlocksDesc, prometheus.GaugeValue, float64(badBlocks))
+	} else {
+		client.ParseError("bad-blocks", resource.BadBlocks, err)
 	}
 	if writeSectTotal, err := parseUint64(resource.WriteSectTotal); err == nil {
 		ch <- prometheus.MustNewConstMetric(c.writeSectTotalDesc, prometheus.CounterValue, float64(writeSectTotal))
+	} else {
+		client.ParseError("write-sect-total", resource.WriteSectTotal, err)
 	}
 
 	// Memory metrics
 	if totalMemory, err := parseUint64(resource.TotalMemory); err == nil {
 		ch <- prometheus.MustNewConstMetric(c.totalMemoryDesc, prometheus.GaugeValue, float64(totalMemory))
+	} else {
+		client.ParseError("total-memory", resource.TotalMemory, err)
 	}
 	if freeMemory, err := parseUint64(resource.FreeMemory); err == nil {
 		ch <- prometheus.MustNewConstMetric(c.freeMemoryDesc, prometheus.GaugeValue, float64(freeMemory))
+	} else {
+		client.ParseError("free-memory", resource.FreeMemory, err)
 	}
 
 	// Uptime metric
-	if uptime := parseUptime(resource.Uptime); uptime > 0 {
-		ch <- prometheus.MustNewConstMetric(c.uptimeDesc, prometheus.GaugeValue, float64(uptime))
+	if uptime, err := routeros.ParseDuration(resource.Uptime); err != nil {
+		client.ParseError("uptime", resource.Uptime, err)
+	} else if uptime > 0 {
+		ch <- prometheus.MustNewConstMetric(c.uptimeDesc, prometheus.GaugeValue, uptime.Seconds())
 	}
 
 	// Fetch system health data
-	health, err := c.fetchSystemHealth(ctx, target, auth)
+	version, _ := routeros.ParseVersion(resource.Version)
+	health, err := c.fetchSystemHealth(ctx, client, version)
 	if err != nil {
 		// Health data is optional, log but don't fail
-		log.Printf("Warning: failed to fetch system health: %v", err)
+		client.Logger().WarnContext(ctx, "Failed to fetch system health", "err", err)
 	} else {
 		// Process health metrics
 		for _, item := range health {
@@ -249,6 +275,8 @@ func (c *Collector) Collect(ctx context.Context, target string, auth collector.A
 				case "temperature":
 					ch <- prometheus.MustNewConstMetric(c.temperatureDesc, prometheus.GaugeValue, value)
 				}
+			} else {
+				client.ParseError(item.Name, item.Value, err)
 			}
 		}
 	}
@@ -257,33 +285,9 @@ func (c *Collector) Collect(ctx context.Context, target string, auth collector.A
 }
 
 // fetchSystemResource fetches system resource data from Mikrotik REST API
-func (c *Collector) fetchSystemResource(ctx context.Context, target string, auth collector.AuthInfo) (*SystemResourceData, error) {
-	url := fmt.Sprintf("http://%s/rest/system/resource", target)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.SetBasicAuth(auth.Username, auth.Password)
-	req.Header.Set("Accept", "application/json")
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
-	}
-
+func (c *Collector) fetchSystemResource(ctx context.Context, client *routeros.Client) (*SystemResourceData, error) {
 	var resource SystemResourceData
-	if err := json.NewDecoder(resp.Body).Decode(&resource); err != nil {
+	if err := client.Get(ctx, "/system/resource", &resource); err != nil {
 		return nil, err
 	}
 
@@ -298,91 +302,26 @@ func parseUint64(s string) (uint64, error) {
 	return strconv.ParseUint(s, 10, 64)
 }
 
-// fetchSystemHealth fetches system health data from Mikrotik REST API
-func (c *Collector) fetchSystemHealth(ctx context.Context, target string, auth collector.AuthInfo) ([]SystemHealthData, error) {
-	url := fmt.Sprintf("http://%s/rest/system/health", target)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
-
-	req.SetBasicAuth(auth.Username, auth.Password)
-	req.Header.Set("Accept", "application/json")
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
-
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+// fetchSystemHealth fetches system health data. RouterOS 6 returns a single entry with
+// one property per sensor, which is mapped to the RouterOS 7 name/value list.
+func (c *Collector) fetchSystemHealth(ctx context.Context, client *routeros.Client, version routeros.Version) ([]SystemHealthData, error) {
+	if version.Major > 0 && version.Major < 7 {
+		var sensors map[string]string
+		if err := client.Get(ctx, "/system/health", &sensors); err != nil {
+			return nil, err
+		}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+		health := make([]SystemHealthData, 0, len(sensors))
+		for name, value := range sensors {
+			health = append(health, SystemHealthData{Name: name, Value: value})
+		}
+		return health, nil
 	}
 
 	var health []SystemHealthData
-	if err := json.NewDecoder(resp.Body).Decode(&health); err != nil {
+	if err := client.Get(ctx, "/system/health", &health); err != nil {
 		return nil, err
 	}
 
 	return health, nil
 }
-
-// parseUptime parses Mikrotik uptime format to seconds
-// Format examples: "2w4d1h12m27s", "1h30m", "45s"
-func parseUptime(uptimeStr string) int64 {
-	if uptimeStr == "" {
-		return 0
-	}
-
-	// Regular expression to match Mikrotik uptime format
-	re := regexp.MustCompile(`(?:(\d+)w)?(?:(\d+)d)?(?:(\d+)h)?(?:(\d+)m)?(?:(\d+)s)?`)
-	matches := re.FindStringSubmatch(uptimeStr)
-
-	if len(matches) == 0 {
-		return 0
-	}
-
-	var totalSeconds int64
-
-	// Parse weeks
-	if matches[1] != "" {
-		if weeks, err := strconv.ParseInt(matches[1], 10, 64); err == nil {
-			totalSeconds += weeks * 7 * 24 * 3600
-		}
-	}
-
-	// Parse days
-	if matches[2] != "" {
-		if days, err := strconv.ParseInt(matches[2], 10, 64); err == nil {
-			totalSeconds += days * 24 * 3600
-		}
-	}
-
-	// Parse hours
-	if matches[3] != "" {
-		if hours, err := strconv.ParseInt(matches[3], 10, 64); err == nil {
-			totalSeconds += hours * 3600
-		}
-	}
-
-	// Parse minutes
-	if matches[4] != "" {
-		if minutes, err := strconv.ParseInt(matches[4], 10, 64); err == nil {
-			totalSeconds += minutes * 60
-		}
-	}
-
-	// Parse seconds
-	if matches[5] != "" {
-		if seconds, err := strconv.ParseInt(matches[5], 10, 64); err == nil {
-			totalSeconds += seconds
-		}
-	}
-
-	return totalSeconds
-}
@@ -0,0 +1,282 @@
+package wireguard
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mikrotik-exporter/collector"
+	"github.com/mikrotik-exporter/config"
+	"github.com/mikrotik-exporter/routeros"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// publicKeyLength is the number of characters of a public key kept in labels
+const publicKeyLength = 8
+
+// Collector implements the collector.Collector interface for WireGuard metrics
+type Collector struct {
+	interfaceUpDesc       *prometheus.Desc
+	interfaceInfoDesc     *prometheus.Desc
+	interfacePeersDesc    *prometheus.Desc
+	peerInfoDesc          *prometheus.Desc
+	peerAllowedDesc       *prometheus.Desc
+	peerRxBytesDesc       *prometheus.Desc
+	peerTxBytesDesc       *prometheus.Desc
+	peerLastHandshakeDesc *prometheus.Desc
+	namespace             string
+}
+
+// WireGuardInterfaceData represents the structure returned by the Mikrotik WireGuard interface API (RouterOS 7)
+type WireGuardInterfaceData struct {
+	ID         string `json:".id"`
+	Name       string `json:"name"`
+	ListenPort string `json:"listen-port"`
+	PublicKey  string `json:"public-key"`
+	Running    string `json:"running"`
+	Disabled   string `json:"disabled"`
+}
+
+// WireGuardPeerData represents the structure returned by the Mikrotik WireGuard peer API.
+// LastHandshake is missing until the first handshake; Name is only reported since RouterOS 7.15.
+type WireGuardPeerData struct {
+	ID                     string `json:".id"`
+	Interface              string `json:"interface"`
+	Name                   string `json:"name"`
+	Comment                string `json:"comment"`
+	PublicKey              string `json:"public-key"`
+	EndpointAddress        string `json:"endpoint-address"`
+	EndpointPort           string `json:"endpoint-port"`
+	CurrentEndpointAddress string `json:"current-endpoint-address"`
+	CurrentEndpointPort    string `json:"current-endpoint-port"`
+	AllowedAddress         string `json:"allowed-address"`
+	Rx                     string `json:"rx"`
+	Tx                     string `json:"tx"`
+	LastHandshake          string `json:"last-handshake"`
+	Disabled               string `json:"disabled"`
+}
+
+// NewCollector creates a new WireGuard collector
+func NewCollector() *Collector {
+	c := &Collector{
+		namespace: "mikrotik_exporter", // default namespace
+	}
+	c.initMetrics()
+	return c
+}
+
+// initMetrics initializes the metric descriptors with the current namespace
+func (c *Collector) initMetrics() {
+	peerLabels := []string{"interface", "public_key"}
+
+	c.interfaceUpDesc = prometheus.NewDesc(
+		c.namespace+"_wireguard_interface_up",
+		"WireGuard interface running status (1 = running, 0 = not running)",
+		[]string{"interface"},
+		nil,
+	)
+	c.interfaceInfoDesc = prometheus.NewDesc(
+		c.namespace+"_wireguard_interface_info",
+		"WireGuard interface information",
+		[]string{"interface", "listen_port", "public_key"},
+		nil,
+	)
+	c.interfacePeersDesc = prometheus.NewDesc(
+		c.namespace+"_wireguard_interface_peers",
+		"Number of enabled WireGuard peers on the interface",
+		[]string{"interface"},
+		nil,
+	)
+	c.peerInfoDesc = prometheus.NewDesc(
+		c.namespace+"_wireguard_peer_info",
+		"WireGuard peer information",
+		[]string{"interface", "public_key", "name", "endpoint_address", "endpoint_port"},
+		nil,
+	)
+	c.peerAllowedDesc = prometheus.NewDesc(
+		c.namespace+"_wireguard_peer_allowed_address",
+		"Address range allowed through the WireGuard peer",
+		[]string{"interface", "public_key", "allowed_address"},
+		nil,
+	)
+	c.peerRxBytesDesc = prometheus.NewDesc(
+		c.namespace+"_wireguard_peer_rx_bytes_total",
+		"Total bytes received from the WireGuard peer",
+		peerLabels,
+		nil,
+	)
+	c.peerTxBytesDesc = prometheus.NewDesc(
+		c.namespace+"_wireguard_peer_tx_bytes_total",
+		"Total bytes transmitted to the WireGuard peer",
+		peerLabels,
+		nil,
+	)
+	c.peerLastHandshakeDesc = prometheus.NewDesc(
+		c.namespace+"_wireguard_peer_last_handshake_age_seconds",
+		"Time since the last handshake with the WireGuard peer in seconds",
+		peerLabels,
+		nil,
+	)
+}
+
+// Name returns the collector name
+func (c *Collector) Name() string {
+	return "wireguard"
+}
+
+// Describe sends the descriptors of each metric over to the provided channel
+func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.interfaceUpDesc
+	ch <- c.interfaceInfoDesc
+	ch <- c.interfacePeersDesc
+	ch <- c.peerInfoDesc
+	ch <- c.peerAllowedDesc
+	ch <- c.peerRxBytesDesc
+	ch <- c.peerTxBytesDesc
+	ch <- c.peerLastHandshakeDesc
+}
+
+// SetNamespace sets the metrics namespace prefix
+func (c *Collector) SetNamespace(namespace string) {
+	c.namespace = namespace
+	c.initMetrics()
+}
+
+// Configure returns the collector; it has no options
+func (c *Collector) Configure(options config.CollectorOptions) (collector.Collector, error) {
+	if err := collector.NoOptions(options); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Collect fetches the metrics from Mikrotik device and sends them to Prometheus
+func (c *Collector) Collect(ctx context.Context, client *routeros.Client, ch chan<- prometheus.Metric) error {
+	interfaces, err := c.fetchInterfaces(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to fetch WireGuard interfaces: %w", err)
+	}
+
+	peers, err := c.fetchPeers(ctx, client)
+	if err != nil {
+		return fmt.Errorf("failed to fetch WireGuard peers: %w", err)
+	}
+
+	// Keys are only shortened if that keeps the peers of an interface apart
+	type shortKey struct{ iface, key string }
+	shortKeys := make(map[shortKey]int)
+	for _, peer := range peers {
+		shortKeys[shortKey{peer.Interface, shortenKey(peer.PublicKey)}]++
+	}
+
+	peerCounts := make(map[string]int)
+	for _, peer := range peers {
+		if peer.Disabled == "true" {
+			continue
+		}
+		peerCounts[peer.Interface]++
+
+		publicKey := shortenKey(peer.PublicKey)
+		if shortKeys[shortKey{peer.Interface, publicKey}] > 1 {
+			publicKey = peer.PublicKey
+		}
+		labels := []string{peer.Interface, publicKey}
+
+		name := peer.Name
+		if name == "" {
+			name = peer.Comment
+		}
+
+		// Road-warrior peers have no configured endpoint; the current one is
+		// learned from the last handshake
+		endpointAddress, endpointPort := peer.CurrentEndpointAddress, peer.CurrentEndpointPort
+		if endpointAddress == "" {
+			endpointAddress, endpointPort = peer.EndpointAddress, peer.EndpointPort
+		}
+		ch <- prometheus.MustNewConstMetric(c.peerInfoDesc, prometheus.GaugeValue, 1,
+			peer.Interface, publicKey, name, endpointAddress, endpointPort)
+
+		// A repeated entry would be a duplicate series and fail the scrape
+		allowed := make(map[string]bool)
+		for _, address := range strings.Split(peer.AllowedAddress, ",") {
+			if address = strings.TrimSpace(address); address != "" && !allowed[address] {
+				allowed[address] = true
+				ch <- prometheus.MustNewConstMetric(c.peerAllowedDesc, prometheus.GaugeValue, 1, peer.Interface, publicKey, address)
+			}
+		}
+
+		if rx, err := strconv.ParseFloat(peer.Rx, 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.peerRxBytesDesc, prometheus.CounterValue, rx, labels...)
+		} else {
+			client.ParseError("rx", peer.Rx, err)
+		}
+		if tx, err := strconv.ParseFloat(peer.Tx, 64); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.peerTxBytesDesc, prometheus.CounterValue, tx, labels...)
+		} else {
+			client.ParseError("tx", peer.Tx, err)
+		}
+
+		// Peers that never completed a handshake have no series
+		if handshake, err := routeros.ParseDuration(peer.LastHandshake); err == nil {
+			ch <- prometheus.MustNewConstMetric(c.peerLastHandshakeDesc, prometheus.GaugeValue, handshake.Seconds(), labels...)
+		} else {
+			client.ParseError("last-handshake", peer.LastHandshake, err)
+		}
+	}
+
+	for _, iface := range interfaces {
+		if iface.Disabled == "true" {
+			continue
+		}
+
+		running := 0.0
+		if iface.Running == "true" {
+			running = 1.0
+		}
+		ch <- prometheus.MustNewConstMetric(c.interfaceUpDesc, prometheus.GaugeValue, running, iface.Name)
+		ch <- prometheus.MustNewConstMetric(c.interfaceInfoDesc, prometheus.GaugeValue, 1,
+			iface.Name, iface.ListenPort, shortenKey(iface.PublicKey))
+		ch <- prometheus.MustNewConstMetric(c.interfacePeersDesc, prometheus.GaugeValue, float64(peerCounts[iface.Name]), iface.Name)
+	}
+
+	return nil
+}
+
+// shortenKey returns the first characters of a public key, which identify a
+// peer well enough without making the labels unreadable
+func shortenKey(key string) string {
+	if len(key) <= publicKeyLength {
+		return key
+	}
+	return key[:publicKeyLength]
+}
+
+// fetchInterfaces fetches the WireGuard interfaces; the private keys are not
+// requested
+func (c *Collector) fetchInterfaces(ctx context.Context, client *routeros.Client) ([]WireGuardInterfaceData, error) {
+	var interfaces []WireGuardInterfaceData
+	err := client.Get(ctx, "/interface/wireguard", &interfaces, routeros.Proplist(
+		".id", "name", "listen-port", "public-key", "running", "disabled",
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	return interfaces, nil
+}
+
+// fetchPeers fetches the WireGuard peers; the preshared keys are not requested
+func (c *Collector) fetchPeers(ctx context.Context, client *routeros.Client) ([]WireGuardPeerData, error) {
+	var peers []WireGuardPeerData
+	err := client.Get(ctx, "/interface/wireguard/peers", &peers, routeros.Proplist(
+		".id", "interface", "name", "comment", "public-key", "endpoint-address", "endpoint-port",
+		"current-endpoint-address", "current-endpoint-port", "allowed-address", "rx", "tx",
+		"last-handshake", "disabled",
+	))
+	if err != nil {
+		return nil, err
+	}
+
+	return peers, nil
+}
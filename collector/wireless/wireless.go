@@ -2,15 +2,15 @@ package wireless
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/mikrotik-exporter/collector"
+	"github.com/mikrotik-exporter/config"
+	"github.com/mikrotik-exporter/routeros"
 	"github.com/prometheus/client_golang/prometheus"
 )
 
@@ -25,7 +25,18 @@ type Collector struct {
 	txRateDesc     *prometheus.Desc
 	uptimeDesc     *prometheus.Desc
 	signalDesc     *prometheus.Desc
-	namespace      string
+	weakDesc       *prometheus.Desc
+
+	// signalThreshold enables the weak client counts; nil if not configured
+	signalThreshold *float64
+
+	namespace string
+}
+
+// Options are the module options of the wireless collector
+type Options struct {
+	// SignalThreshold in dBm; clients with a weaker signal are counted per interface
+	SignalThreshold *float64 `yaml:"signal_threshold,omitempty"`
 }
 
 // WirelessRegistrationData represents the structure returned by Mikrotik WiFi registration table API
@@ -45,6 +56,27 @@ type WirelessRegistrationData struct {
 	Uptime       string `json:"uptime"`
 }
 
+// WirelessRegistrationDataV6 represents the structure returned by the legacy wireless
+// registration table API (RouterOS 6, or RouterOS 7 with the wireless package)
+type WirelessRegistrationDataV6 struct {
+	ID             string `json:".id"`
+	Authorized     string `json:"authenticated"`
+	Bytes          string `json:"bytes"`
+	Interface      string `json:"interface"`
+	MacAddress     string `json:"mac-address"`
+	Packets        string `json:"packets"`
+	RxRate         string `json:"rx-rate"`
+	SignalStrength string `json:"signal-strength"`
+	TxRate         string `json:"tx-rate"`
+	Uptime         string `json:"uptime"`
+}
+
+// WirelessInterfaceDataV6 represents the legacy wireless interface API, used to look up SSIDs
+type WirelessInterfaceDataV6 struct {
+	Name string `json:"name"`
+	SSID string `json:"ssid"`
+}
+
 // NewCollector creates a new wireless collector
 func NewCollector() *Collector {
 	c := &Collector{
@@ -104,6 +136,11 @@ func (c *Collector) initMetrics() {
 		"Wireless client signal strength in dBm",
 		macLabel, nil,
 	)
+	c.weakDesc = prometheus.NewDesc(
+		c.namespace+"_wireless_weak_clients",
+		"Number of wireless clients with a signal below the configured threshold",
+		[]string{"interface"}, nil,
+	)
 }
 
 // Name returns the collector name
@@ -122,6 +159,7 @@ func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.txRateDesc
 	ch <- c.uptimeDesc
 	ch <- c.signalDesc
+	ch <- c.weakDesc
 }
 
 // SetNamespace sets the metrics namespace prefix
@@ -130,14 +168,32 @@ func (c *Collector) SetNamespace(namespace string) {
 	c.initMetrics()
 }
 
+// Configure returns a copy of the collector with the signal threshold set
+func (c *Collector) Configure(options config.CollectorOptions) (collector.Collector, error) {
+	var opts Options
+	if err := options.Decode(&opts); err != nil {
+		return nil, err
+	}
+	if opts.SignalThreshold != nil && *opts.SignalThreshold > 0 {
+		return nil, fmt.Errorf("signal_threshold must be negative (dBm)")
+	}
+
+	clone := *c
+	clone.signalThreshold = opts.SignalThreshold
+	return &clone, nil
+}
+
 // Collect fetches the metrics from Mikrotik device and sends them to Prometheus
-func (c *Collector) Collect(ctx context.Context, target string, auth collector.AuthInfo, ch chan<- prometheus.Metric) error {
+func (c *Collector) Collect(ctx context.Context, client *routeros.Client, ch chan<- prometheus.Metric) error {
 	// Fetch wireless registration data from Mikrotik REST API
-	registrations, err := c.fetchWirelessRegistrations(ctx, target, auth)
+	registrations, err := c.fetchWirelessRegistrations(ctx, client)
 	if err != nil {
 		return fmt.Errorf("failed to fetch wireless registrations: %w", err)
 	}
 
+	// Clients below the signal threshold by interface
+	weakClients := make(map[string]int)
+
 	// Process each wireless client
 	for _, reg := range registrations {
 		clientInfoLabels := []string{reg.MacAddress, reg.Interface, reg.SSID}
@@ -150,65 +206,115 @@ func (c *Collector) Collect(ctx context.Context, target string, auth collector.A
 		if txBytes, rxBytes, err := parseCommaSeparatedPair(reg.Bytes); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.txBytesDesc, prometheus.CounterValue, float64(txBytes), macLabels...)
 			ch <- prometheus.MustNewConstMetric(c.rxBytesDesc, prometheus.CounterValue, float64(rxBytes), macLabels...)
+		} else {
+			client.ParseError("bytes", reg.Bytes, err)
 		}
 
 		// Parse packets (format: "tx_packets,rx_packets")
 		if txPackets, rxPackets, err := parseCommaSeparatedPair(reg.Packets); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.txPacketsDesc, prometheus.CounterValue, float64(txPackets), macLabels...)
 			ch <- prometheus.MustNewConstMetric(c.rxPacketsDesc, prometheus.CounterValue, float64(rxPackets), macLabels...)
+		} else {
+			client.ParseError("packets", reg.Packets, err)
 		}
 
 		// RX/TX rates
-		if rxRate, err := parseUint64(reg.RxRate); err == nil {
+		if rxRate, err := parseRate(reg.RxRate); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.rxRateDesc, prometheus.GaugeValue, float64(rxRate), macLabels...)
+		} else {
+			client.ParseError("rx-rate", reg.RxRate, err)
 		}
-		if txRate, err := parseUint64(reg.TxRate); err == nil {
+		if txRate, err := parseRate(reg.TxRate); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.txRateDesc, prometheus.GaugeValue, float64(txRate), macLabels...)
+		} else {
+			client.ParseError("tx-rate", reg.TxRate, err)
 		}
 
 		// Uptime
-		if uptime := parseUptime(reg.Uptime); uptime > 0 {
-			ch <- prometheus.MustNewConstMetric(c.uptimeDesc, prometheus.GaugeValue, float64(uptime), macLabels...)
+		if uptime, err := routeros.ParseDuration(reg.Uptime); err != nil {
+			client.ParseError("uptime", reg.Uptime, err)
+		} else if uptime > 0 {
+			ch <- prometheus.MustNewConstMetric(c.uptimeDesc, prometheus.GaugeValue, uptime.Seconds(), macLabels...)
 		}
 
-		// Signal strength
-		if signal, err := strconv.ParseFloat(reg.Signal, 64); err == nil {
+		// Signal strength (legacy format: "-65@6Mbps")
+		signal, _, _ := strings.Cut(reg.Signal, "@")
+		if signal, err := strconv.ParseFloat(signal, 64); err == nil {
 			ch <- prometheus.MustNewConstMetric(c.signalDesc, prometheus.GaugeValue, signal, macLabels...)
+			if c.signalThreshold != nil {
+				// Interfaces without weak clients report 0
+				weak := 0
+				if signal < *c.signalThreshold {
+					weak = 1
+				}
+				weakClients[reg.Interface] += weak
+			}
+		} else {
+			client.ParseError("signal", reg.Signal, err)
 		}
 	}
 
+	for iface, count := range weakClients {
+		ch <- prometheus.MustNewConstMetric(c.weakDesc, prometheus.GaugeValue, float64(count), iface)
+	}
+
 	return nil
 }
 
-// fetchWirelessRegistrations fetches wireless registration data from Mikrotik REST API
-func (c *Collector) fetchWirelessRegistrations(ctx context.Context, target string, auth collector.AuthInfo) ([]WirelessRegistrationData, error) {
-	url := fmt.Sprintf("http://%s/rest/interface/wifi/registration-table", target)
-
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+// fetchWirelessRegistrations fetches wireless registration data. RouterOS 7 devices use
+// the wifi menu, falling back to the legacy wireless menu if the wifi package is not installed.
+func (c *Collector) fetchWirelessRegistrations(ctx context.Context, client *routeros.Client) ([]WirelessRegistrationData, error) {
+	version, err := client.Version(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	req.SetBasicAuth(auth.Username, auth.Password)
-	req.Header.Set("Accept", "application/json")
-
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	if version.Major >= 7 {
+		var registrations []WirelessRegistrationData
+		err := client.Get(ctx, "/interface/wifi/registration-table", &registrations)
+		if err == nil {
+			return registrations, nil
+		}
+		if !errors.Is(err, routeros.ErrNotFound) {
+			return nil, err
+		}
 	}
 
-	resp, err := client.Do(req)
-	if err != nil {
+	return c.fetchLegacyRegistrations(ctx, client)
+}
+
+// fetchLegacyRegistrations fetches the legacy wireless registration table and maps it
+// to the wifi structure
+func (c *Collector) fetchLegacyRegistrations(ctx context.Context, client *routeros.Client) ([]WirelessRegistrationData, error) {
+	var legacy []WirelessRegistrationDataV6
+	if err := client.Get(ctx, "/interface/wireless/registration-table", &legacy); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP %d: %s", resp.StatusCode, resp.Status)
+	// The legacy registration table has no SSID, so look it up by interface
+	var interfaces []WirelessInterfaceDataV6
+	ssids := make(map[string]string)
+	if err := client.Get(ctx, "/interface/wireless", &interfaces, routeros.Proplist("name", "ssid")); err == nil {
+		for _, iface := range interfaces {
+			ssids[iface.Name] = iface.SSID
+		}
 	}
 
-	var registrations []WirelessRegistrationData
-	if err := json.NewDecoder(resp.Body).Decode(&registrations); err != nil {
-		return nil, err
+	registrations := make([]WirelessRegistrationData, 0, len(legacy))
+	for _, reg := range legacy {
+		registrations = append(registrations, WirelessRegistrationData{
+			ID:         reg.ID,
+			Authorized: reg.Authorized,
+			Bytes:      reg.Bytes,
+			Interface:  reg.Interface,
+			MacAddress: reg.MacAddress,
+			Packets:    reg.Packets,
+			RxRate:     reg.RxRate,
+			Signal:     reg.SignalStrength,
+			SSID:       ssids[reg.Interface],
+			TxRate:     reg.TxRate,
+			Uptime:     reg.Uptime,
+		})
 	}
 
 	return registrations, nil
@@ -246,57 +352,26 @@ func parseUint64(s string) (uint64, error) {
 	return strconv.ParseUint(s, 10, 64)
 }
 
-// parseUptime converts Mikrotik uptime format to seconds
-// Format examples: "2w4d1h12m27s", "1h30m", "45s"
-func parseUptime(uptimeStr string) int64 {
-	if uptimeStr == "" {
-		return 0
-	}
-
-	// Regular expression to match Mikrotik uptime format
-	re := regexp.MustCompile(`(?:(\d+)w)?(?:(\d+)d)?(?:(\d+)h)?(?:(\d+)m)?(?:(\d+)s)?`)
-	matches := re.FindStringSubmatch(uptimeStr)
-
-	if len(matches) == 0 {
-		return 0
-	}
-
-	var totalSeconds int64
-
-	// Parse weeks
-	if matches[1] != "" {
-		if weeks, err := strconv.ParseInt(matches[1], 10, 64); err == nil {
-			totalSeconds += weeks * 7 * 24 * 3600
-		}
-	}
-
-	// Parse days
-	if matches[2] != "" {
-		if days, err := strconv.ParseInt(matches[2], 10, 64); err == nil {
-			totalSeconds += days * 24 * 3600
-		}
+// parseRate parses a rate in bits per second. Both plain numbers and the
+// "130Mbps-20MHz/2S/SGI" format are accepted.
+func parseRate(s string) (uint64, error) {
+	if value, err := parseUint64(s); err == nil {
+		return value, nil
 	}
 
-	// Parse hours
-	if matches[3] != "" {
-		if hours, err := strconv.ParseInt(matches[3], 10, 64); err == nil {
-			totalSeconds += hours * 3600
-		}
+	match := rateRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return 0, fmt.Errorf("invalid rate: %s", s)
 	}
 
-	// Parse minutes
-	if matches[4] != "" {
-		if minutes, err := strconv.ParseInt(matches[4], 10, 64); err == nil {
-			totalSeconds += minutes * 60
-		}
-	}
-
-	// Parse seconds
-	if matches[5] != "" {
-		if seconds, err := strconv.ParseInt(matches[5], 10, 64); err == nil {
-			totalSeconds += seconds
-		}
+	value, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, err
 	}
 
-	return totalSeconds
+	multipliers := map[string]float64{"": 1, "k": 1e3, "M": 1e6, "G": 1e9}
+	return uint64(value * multipliers[match[2]]), nil
 }
+
+// rateRegexp matches the leading rate of legacy wireless rate strings
+var rateRegexp = regexp.MustCompile(`^(\d+(?:\.\d+)?)([kMG]?)bps`)
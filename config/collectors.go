@@ -0,0 +1,102 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CollectorConfig enables a collector in a module. In YAML it is either a
+// boolean or a mapping of options, which enables the collector:
+//
+//	collectors:
+//	  system: true
+//	  interfaces:
+//	    exclude: "vlan.*"
+type CollectorConfig struct {
+	Enabled bool
+	Options CollectorOptions
+}
+
+// CollectorOptions are the collector-specific settings of a module
+type CollectorOptions map[string]interface{}
+
+// Collectors describes the collectors that modules can enable
+type Collectors interface {
+	// List returns the names of all collectors
+	List() []string
+
+	// ValidateOptions checks the options of the named collector
+	ValidateOptions(name string, options CollectorOptions) error
+
+	// ValidateMetrics checks that the metrics of the built-in collectors, the
+	// given custom collectors and the exporter itself can be registered
+	// together, with the labels of a target added to every metric
+	ValidateMetrics(custom map[string]CustomCollectorConfig, labels map[string]string) error
+}
+
+// UnmarshalYAML accepts a boolean or a mapping of options
+func (c *CollectorConfig) UnmarshalYAML(node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		c.Options = nil
+		return node.Decode(&c.Enabled)
+	case yaml.MappingNode:
+		c.Enabled = true
+		return node.Decode(&c.Options)
+	default:
+		return fmt.Errorf("line %d: collector must be true, false or a mapping of options", node.Line)
+	}
+}
+
+// MarshalYAML writes the options, or the enabled flag if there are none
+func (c CollectorConfig) MarshalYAML() (interface{}, error) {
+	if c.Enabled && len(c.Options) > 0 {
+		return c.Options, nil
+	}
+	return c.Enabled, nil
+}
+
+// Decode decodes the options into out, typically a pointer to a struct with
+// yaml tags. Unknown options are rejected.
+func (o CollectorOptions) Decode(out interface{}) error {
+	if len(o) == 0 {
+		return nil
+	}
+
+	data, err := yaml.Marshal(map[string]interface{}(o))
+	if err != nil {
+		return err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(out); err != nil && err != io.EOF {
+		var typeErr *yaml.TypeError
+		if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+			// The line numbers refer to the re-encoded options, not the file
+			msg := stripYAMLLine(typeErr.Errors[0])
+			if match := unknownField.FindStringSubmatch(msg); match != nil {
+				return fmt.Errorf("unknown option '%s'", match[1])
+			}
+			return fmt.Errorf("%s", msg)
+		}
+		return err
+	}
+	return nil
+}
+
+// unknownField matches the yaml.v3 error for unknown fields
+var unknownField = regexp.MustCompile(`^field (\S+) not found in type`)
+
+// stripYAMLLine removes the "line N: " prefix of yaml.v3 errors
+func stripYAMLLine(msg string) string {
+	if match := yamlErrorLine.FindStringSubmatch(msg); match != nil {
+		return match[2]
+	}
+	return msg
+}
@@ -1,8 +1,15 @@
 package config
 
 import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strconv"
+	"time"
 
 	"gopkg.in/yaml.v3"
 )
@@ -11,20 +18,81 @@ import (
 type Config struct {
 	Auths   map[string]AuthConfig   `yaml:"auths"`
 	Modules map[string]ModuleConfig `yaml:"modules"`
+	Targets []TargetConfig          `yaml:"targets,omitempty"`
+
+	// Discovery assigns auth profiles and modules to devices found by MNDP
+	Discovery DiscoveryConfig `yaml:"discovery,omitempty"`
+
+	// CustomCollectors defines collectors for arbitrary RouterOS menus; modules
+	// enable them by name like the built-in collectors
+	CustomCollectors map[string]CustomCollectorConfig `yaml:"custom_collectors,omitempty"`
+
+	// root is the parsed document, kept to report line numbers
+	root yaml.Node
 }
 
 // AuthConfig represents authentication configuration
 type AuthConfig struct {
 	Username string `yaml:"username"`
-	Password string `yaml:"password"`
+	Password Secret `yaml:"password"`
+
+	// PasswordFile is read instead of Password on every (re)load
+	PasswordFile string `yaml:"password_file,omitempty"`
+
+	Transport string    `yaml:"transport"`
+	Scheme    string    `yaml:"scheme"`
+	TLSConfig TLSConfig `yaml:"tls_config,omitempty"`
+
+	tlsConfig *tls.Config
+}
+
+// TLSConfig represents the TLS settings used to connect to devices over HTTPS
+type TLSConfig struct {
+	CAFile             string `yaml:"ca_file,omitempty"`
+	CertFile           string `yaml:"cert_file,omitempty"`
+	KeyFile            string `yaml:"key_file,omitempty"`
+	ServerName         string `yaml:"server_name,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty"`
 }
 
+// DefaultConcurrency is the number of collectors run at the same time against one
+// device, across all probes of it
+const DefaultConcurrency = 4
+
 // ModuleConfig represents module configuration
 type ModuleConfig struct {
-	Collectors map[string]bool `yaml:"collectors"`
+	// Collectors enables collectors by name, optionally with options
+	Collectors  map[string]CollectorConfig `yaml:"collectors"`
+	Concurrency int                        `yaml:"concurrency,omitempty"`
+
+	// FailOnError makes the probe return an error instead of partial results
+	// when any collector fails
+	FailOnError bool `yaml:"fail_on_error,omitempty"`
+
+	// Timeout limits the whole probe; the scrape timeout sent by Prometheus
+	// still applies if it is shorter
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	// CollectorTimeouts limits individual collectors by name
+	CollectorTimeouts map[string]time.Duration `yaml:"collector_timeouts,omitempty"`
 }
 
-// LoadConfig loads configuration from the specified file
+// GetCollectorTimeout returns the timeout of the named collector, or 0 if it
+// is only limited by the probe deadline
+func (m ModuleConfig) GetCollectorTimeout(name string) time.Duration {
+	return m.CollectorTimeouts[name]
+}
+
+// GetConcurrency returns the number of collectors to run against the device at the same time
+func (m ModuleConfig) GetConcurrency() int {
+	if m.Concurrency <= 0 {
+		return DefaultConcurrency
+	}
+	return m.Concurrency
+}
+
+// LoadConfig loads configuration from the specified file. Unknown fields are
+// rejected; use Validate to check references to collectors.
 func LoadConfig(filename string) (*Config, error) {
 	data, err := os.ReadFile(filename)
 	if err != nil {
@@ -32,13 +100,169 @@ func LoadConfig(filename string) (*Config, error) {
 	}
 
 	var config Config
-	if err := yaml.Unmarshal(data, &config); err != nil {
+	if err := yaml.Unmarshal(data, &config.root); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
+	if err := expandEnv(&config.root); err != nil {
+		return nil, err
+	}
+
+	// Unknown fields are looked up in the file as written; the values are
+	// decoded from the document with the environment references expanded
+	var errs ValidationErrors
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&Config{}); err != nil && err != io.EOF {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
+		for _, msg := range typeErr.Errors {
+			if unknownField.MatchString(stripYAMLLine(msg)) {
+				errs = append(errs, parseYAMLError(msg))
+			}
+		}
+	}
+	if config.root.Kind != 0 {
+		if err := config.root.Decode(&config); err != nil {
+			var typeErr *yaml.TypeError
+			if !errors.As(err, &typeErr) {
+				return nil, fmt.Errorf("failed to parse config file: %w", err)
+			}
+			for _, msg := range typeErr.Errors {
+				errs = append(errs, parseYAMLError(msg))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		errs.sort()
+		return nil, errs
+	}
+
+	for name, auth := range config.Auths {
+		if err := auth.init(); err != nil {
+			errs = append(errs, ValidationError{
+				Line:    config.line("auths", name),
+				Message: fmt.Sprintf("auth configuration '%s': %v", name, err),
+			})
+			continue
+		}
+		config.Auths[name] = auth
+	}
+	if len(errs) > 0 {
+		errs.sort()
+		return nil, errs
+	}
+
+	for i := range config.Targets {
+		config.Targets[i].init()
+	}
+
+	for i := range config.Discovery.Rules {
+		if err := config.Discovery.Rules[i].init(); err != nil {
+			errs = append(errs, ValidationError{
+				Line:    config.line("discovery", "rules", strconv.Itoa(i)),
+				Message: fmt.Sprintf("discovery rule %d: %v", i+1, err),
+			})
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
 
 	return &config, nil
 }
 
+// String returns the configuration as YAML with all secrets redacted
+func (c *Config) String() string {
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return fmt.Sprintf("<error marshaling config: %v>", err)
+	}
+	return string(data)
+}
+
+// init reads the password file, validates the transport and scheme and
+// prepares the TLS configuration of an auth profile
+func (a *AuthConfig) init() error {
+	if a.PasswordFile != "" {
+		if a.Password != "" {
+			return fmt.Errorf("password and password_file are mutually exclusive")
+		}
+		password, err := readSecretFile(a.PasswordFile)
+		if err != nil {
+			return err
+		}
+		a.Password = password
+	}
+
+	switch a.Transport {
+	case "":
+		a.Transport = "rest"
+	case "rest", "api":
+	default:
+		return fmt.Errorf("unsupported transport '%s' (expected rest or api)", a.Transport)
+	}
+
+	switch a.Scheme {
+	case "":
+		a.Scheme = "http"
+	case "http", "https":
+	default:
+		return fmt.Errorf("unsupported scheme '%s' (expected http or https)", a.Scheme)
+	}
+
+	if a.Scheme != "https" {
+		return nil
+	}
+
+	tlsConfig, err := a.TLSConfig.build()
+	if err != nil {
+		return err
+	}
+	a.tlsConfig = tlsConfig
+	return nil
+}
+
+// TLS returns the TLS configuration for HTTPS/api-ssl connections, or nil for plain connections
+func (a AuthConfig) TLS() *tls.Config {
+	return a.tlsConfig
+}
+
+// build creates a tls.Config from the configured files and options
+func (t TLSConfig) build() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	if t.CAFile != "" {
+		pem, err := os.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file '%s'", t.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if (t.CertFile == "") != (t.KeyFile == "") {
+		return nil, fmt.Errorf("cert_file and key_file must be set together")
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	return tlsConfig, nil
+}
+
 // GetAuth returns the authentication configuration for the given name
 func (c *Config) GetAuth(name string) (AuthConfig, error) {
 	auth, exists := c.Auths[name]
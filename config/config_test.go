@@ -0,0 +1,330 @@
+package config
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mikrotik-exporter/routeros"
+)
+
+// testCollectors is a Collectors implementation with a fixed list of
+// collectors that accept no options
+type testCollectors []string
+
+func (c testCollectors) List() []string {
+	return c
+}
+
+func (c testCollectors) ValidateOptions(name string, options CollectorOptions) error {
+	for option := range options {
+		return fmt.Errorf("unknown option '%s'", option)
+	}
+	return nil
+}
+
+func (c testCollectors) ValidateMetrics(custom map[string]CustomCollectorConfig, labels map[string]string) error {
+	return nil
+}
+
+// writeFile writes content to a file in the test's temporary directory
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+// loadConfig loads a configuration from a string
+func loadConfig(t *testing.T, content string) (*Config, error) {
+	t.Helper()
+	return LoadConfig(writeFile(t, "config.yaml", content))
+}
+
+func TestTLSConfig(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"version":"7.12 (stable)"}`)
+	}))
+	// Rejected handshakes are expected
+	server.Config.ErrorLog = log.New(io.Discard, "", 0)
+	server.StartTLS()
+	defer server.Close()
+
+	caFile := writeFile(t, "ca.pem", string(pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: server.Certificate().Raw,
+	})))
+	address := strings.TrimPrefix(server.URL, "https://")
+
+	tests := []struct {
+		name      string
+		tlsConfig string
+		wantErr   string
+	}{
+		{
+			name:    "system roots",
+			wantErr: "certificate",
+		},
+		{
+			name:      "ca_file",
+			tlsConfig: "{ca_file: " + caFile + "}",
+		},
+		{
+			name:      "ca_file with server_name",
+			tlsConfig: "{ca_file: " + caFile + ", server_name: example.com}",
+		},
+		{
+			name:      "ca_file with wrong server_name",
+			tlsConfig: "{ca_file: " + caFile + ", server_name: router.example.net}",
+			wantErr:   "router.example.net",
+		},
+		{
+			name:      "insecure_skip_verify",
+			tlsConfig: "{insecure_skip_verify: true}",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "auths:\n  default:\n    username: admin\n    password: admin\n    scheme: https\n"
+			if tt.tlsConfig != "" {
+				content += "    tls_config: " + tt.tlsConfig + "\n"
+			}
+			content += "modules:\n  default:\n    collectors:\n      system: true\n"
+
+			conf, err := loadConfig(t, content)
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			auth, err := conf.GetAuth("default")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			client, err := routeros.NewPool().Client(address, routeros.Auth{
+				Name:     "default",
+				Username: auth.Username,
+				Password: string(auth.Password),
+				Scheme:   auth.Scheme,
+				TLS:      auth.TLS(),
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			var resource struct {
+				Version string `json:"version"`
+			}
+			err = client.Get(ctx, "/system/resource", &resource)
+
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Get() error = %v", err)
+				}
+				if resource.Version != "7.12 (stable)" {
+					t.Errorf("Get() version = %q, want %q", resource.Version, "7.12 (stable)")
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Get() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTLSConfigErrors(t *testing.T) {
+	emptyFile := writeFile(t, "empty.pem", "")
+
+	tests := []struct {
+		name      string
+		tlsConfig string
+		wantErr   string
+	}{
+		{
+			name:      "missing ca_file",
+			tlsConfig: "ca_file: /nonexistent/ca.pem",
+			wantErr:   "failed to read CA file",
+		},
+		{
+			name:      "ca_file without certificates",
+			tlsConfig: "ca_file: " + emptyFile,
+			wantErr:   "no certificates found",
+		},
+		{
+			name:      "cert_file without key_file",
+			tlsConfig: "cert_file: " + emptyFile,
+			wantErr:   "cert_file and key_file must be set together",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadConfig(t, "auths:\n  default:\n    scheme: https\n    tls_config: {"+tt.tlsConfig+"}\nmodules: {}\n")
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadConfig() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateTLSConfigRequiresHTTPS(t *testing.T) {
+	tests := []struct {
+		name    string
+		scheme  string
+		wantErr bool
+	}{
+		{name: "https", scheme: "https"},
+		{name: "http", scheme: "http", wantErr: true},
+		{name: "default scheme", scheme: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := "auths:\n  default:\n    username: admin\n    password: admin\n"
+			if tt.scheme != "" {
+				content += "    scheme: " + tt.scheme + "\n"
+			}
+			content += "    tls_config:\n      server_name: router.example.net\n"
+			content += "modules:\n  default:\n    collectors:\n      system: true\n"
+
+			conf, err := loadConfig(t, content)
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+
+			_, err = conf.Validate(testCollectors{"system"})
+			if tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), "auth configuration 'default': tls_config is only used with scheme https") {
+					t.Fatalf("Validate() error = %v, want tls_config error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Validate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	values := map[string]string{
+		"TEST_TAG":     "!Passw0rd",
+		"TEST_COMMENT": "ab #cd",
+		"TEST_ALIAS":   "*secret",
+		"TEST_MAPPING": "a: b",
+		"TEST_QUOTES":  `it's "quoted"`,
+		"TEST_EMPTY":   "",
+		"TEST_TIMEOUT": "15s",
+		"TEST_SITE":    "fra",
+		"TEST_NULL":    "null",
+	}
+	for name, value := range values {
+		t.Setenv(name, value)
+	}
+
+	conf, err := loadConfig(t, `
+auths:
+  tag:
+    username: ${TEST_TAG}
+    password: ${TEST_TAG}
+  comment:
+    username: admin # ${TEST_UNSET} in a comment is ignored
+    password: ${TEST_COMMENT}
+  alias:
+    username: "${TEST_ALIAS}"
+    password: '${TEST_ALIAS}'
+  mapping:
+    username: ${TEST_MAPPING}
+    password: ${TEST_QUOTES}
+  empty:
+    username: ${TEST_EMPTY}
+    password: ${TEST_NULL}
+modules:
+  default:
+    timeout: ${TEST_TIMEOUT}
+    collectors:
+      system: true
+targets:
+  - name: router-${TEST_SITE}
+    labels:
+      site: ${TEST_SITE}
+`)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	tests := []struct {
+		auth     string
+		username string
+		password Secret
+	}{
+		{"tag", "!Passw0rd", "!Passw0rd"},
+		{"comment", "admin", "ab #cd"},
+		{"alias", "*secret", "*secret"},
+		{"mapping", "a: b", `it's "quoted"`},
+		{"empty", "", "null"},
+	}
+	for _, tt := range tests {
+		auth := conf.Auths[tt.auth]
+		if auth.Username != tt.username || auth.Password != tt.password {
+			t.Errorf("auth %s = %q/%q, want %q/%q", tt.auth, auth.Username, string(auth.Password), tt.username, string(tt.password))
+		}
+	}
+
+	if timeout := conf.Modules["default"].Timeout; timeout != 15*time.Second {
+		t.Errorf("timeout = %v, want 15s", timeout)
+	}
+	if target := conf.Targets[0]; target.Name != "router-fra" || target.Labels["site"] != "fra" {
+		t.Errorf("target = %+v, want router-fra with site=fra", target)
+	}
+}
+
+func TestExpandEnvErrors(t *testing.T) {
+	t.Setenv("TEST_TIMEOUT", "soon")
+
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{
+			name:    "unset variable",
+			content: "auths:\n  default:\n    password: ${TEST_UNSET}\nmodules: {}\n",
+			wantErr: "environment variables referenced in config file are not set: TEST_UNSET",
+		},
+		{
+			name:    "invalid value",
+			content: "auths: {}\nmodules:\n  default:\n    timeout: ${TEST_TIMEOUT}\n",
+			wantErr: "line 4: cannot unmarshal !!str `soon`",
+		},
+		{
+			name:    "unknown field",
+			content: "auths: {}\nmodules:\n  default:\n    timeout: ${TEST_TIMEOUT}\n    colectors: {}\n",
+			wantErr: "line 5: field colectors not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadConfig(t, tt.content)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("LoadConfig() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package config
+
+import "regexp"
+
+// Metric types of custom collectors
+const (
+	MetricTypeGauge   = "gauge"
+	MetricTypeCounter = "counter"
+)
+
+// Value transforms of custom collector metrics
+const (
+	// TransformNone parses the value as a number
+	TransformNone = ""
+
+	// TransformBool maps true/yes to 1 and false/no to 0
+	TransformBool = "bool"
+
+	// TransformDuration converts RouterOS durations such as "2w4d1h" to seconds
+	TransformDuration = "duration"
+
+	// TransformBytes parses numbers with unit suffixes such as "10M" or "1.5GiB"
+	TransformBytes = "bytes"
+
+	// TransformRxTx splits "rx/tx" pairs into two series with a direction label
+	TransformRxTx = "rx_tx"
+)
+
+// DirectionLabel is the label added by the rx_tx transform
+const DirectionLabel = "direction"
+
+// transforms lists the supported value transforms
+var transforms = map[string]bool{
+	TransformNone:     true,
+	TransformBool:     true,
+	TransformDuration: true,
+	TransformBytes:    true,
+	TransformRxTx:     true,
+}
+
+// metricName matches valid Prometheus metric names
+var metricName = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+
+// CustomCollectorConfig defines a collector for an arbitrary RouterOS menu.
+// Every entry of the menu becomes one sample of each metric; entries with the
+// same label values are added up.
+type CustomCollectorConfig struct {
+	// Path is the menu path, e.g. "/ip/pool/used"
+	Path string `yaml:"path"`
+
+	// Filter only returns entries whose properties match the given values
+	Filter map[string]string `yaml:"filter,omitempty"`
+
+	// Labels maps label names to the properties they are taken from
+	Labels map[string]string `yaml:"labels,omitempty"`
+
+	Metrics []CustomMetricConfig `yaml:"metrics"`
+}
+
+// CustomMetricConfig defines a metric of a custom collector
+type CustomMetricConfig struct {
+	// Name is appended to the metrics namespace
+	Name string `yaml:"name"`
+	Help string `yaml:"help,omitempty"`
+
+	// Property holds the value; without a property the entries are counted
+	Property string `yaml:"property,omitempty"`
+
+	// Type is gauge (default) or counter
+	Type string `yaml:"type,omitempty"`
+
+	// Transform converts the property value to a number
+	Transform string `yaml:"transform,omitempty"`
+}
+
+// GetType returns the metric type, defaulting to gauge
+func (m CustomMetricConfig) GetType() string {
+	if m.Type == "" {
+		return MetricTypeGauge
+	}
+	return m.Type
+}
+
+// Proplist returns the properties the collector needs from the device
+func (c CustomCollectorConfig) Proplist() []string {
+	var properties []string
+	seen := make(map[string]bool)
+	add := func(property string) {
+		if property != "" && !seen[property] {
+			seen[property] = true
+			properties = append(properties, property)
+		}
+	}
+	for _, label := range sortedKeys(c.Labels) {
+		add(c.Labels[label])
+	}
+	for _, metric := range c.Metrics {
+		add(metric.Property)
+	}
+	return properties
+}
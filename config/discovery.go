@@ -0,0 +1,74 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// DiscoveryConfig configures how devices found by MNDP discovery are probed
+type DiscoveryConfig struct {
+	// Rules are evaluated in order; the first matching rule applies. Devices
+	// not matching any rule use the default auth and module.
+	Rules []DiscoveryRule `yaml:"rules,omitempty"`
+}
+
+// DiscoveryRule assigns an auth profile, module and labels to discovered
+// devices by board name and/or identity
+type DiscoveryRule struct {
+	// Board and Identity are regular expressions that must match the whole
+	// value; empty patterns match every device
+	Board    string `yaml:"board,omitempty"`
+	Identity string `yaml:"identity,omitempty"`
+
+	Auth   string            `yaml:"auth,omitempty"`
+	Module string            `yaml:"module,omitempty"`
+	Labels map[string]string `yaml:"labels,omitempty"`
+
+	board    *regexp.Regexp
+	identity *regexp.Regexp
+}
+
+// init compiles the patterns of the rule
+func (r *DiscoveryRule) init() error {
+	var err error
+	if r.board, err = CompileAnchored(r.Board); err != nil {
+		return fmt.Errorf("invalid board pattern: %w", err)
+	}
+	if r.identity, err = CompileAnchored(r.Identity); err != nil {
+		return fmt.Errorf("invalid identity pattern: %w", err)
+	}
+	return nil
+}
+
+// Matches reports whether the rule applies to a device
+func (r DiscoveryRule) Matches(board, identity string) bool {
+	if r.board != nil && !r.board.MatchString(board) {
+		return false
+	}
+	if r.identity != nil && !r.identity.MatchString(identity) {
+		return false
+	}
+	return true
+}
+
+// MatchDiscoveryRule returns the first rule matching a discovered device
+func (c *Config) MatchDiscoveryRule(board, identity string) (DiscoveryRule, bool) {
+	for _, rule := range c.Discovery.Rules {
+		if rule.Matches(board, identity) {
+			return rule, true
+		}
+	}
+	return DiscoveryRule{}, false
+}
+
+// CompileAnchored compiles a pattern that must match the whole string, or
+// returns nil for an empty pattern
+func CompileAnchored(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		return nil, err
+	}
+	return regexp.MustCompile("^(?:" + pattern + ")$"), nil
+}
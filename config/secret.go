@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Secret is a string that is redacted when the configuration is printed
+type Secret string
+
+// redacted is shown instead of secret values
+const redacted = "<secret>"
+
+// String implements fmt.Stringer so secrets are not printed by accident
+func (s Secret) String() string {
+	if s == "" {
+		return ""
+	}
+	return redacted
+}
+
+// MarshalYAML redacts the secret when the configuration is marshaled
+func (s Secret) MarshalYAML() (interface{}, error) {
+	return s.String(), nil
+}
+
+// envReference matches ${VAR} references in the configuration file
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// expandEnv replaces ${VAR} references in the scalar values of the parsed
+// document with the values of the environment variables. The values are not
+// parsed as YAML, so they may contain any character; only their type is
+// resolved again, e.g. for numbers. Referencing an unset variable is an error.
+func expandEnv(node *yaml.Node) error {
+	var missing []string
+	seen := make(map[string]bool)
+	expand := func(ref string) string {
+		name := envReference.FindStringSubmatch(ref)[1]
+		value, exists := os.LookupEnv(name)
+		if !exists {
+			if !seen[name] {
+				missing = append(missing, name)
+				seen[name] = true
+			}
+			return ref
+		}
+		return value
+	}
+
+	var walk func(node *yaml.Node)
+	walk = func(node *yaml.Node) {
+		switch node.Kind {
+		case yaml.DocumentNode, yaml.SequenceNode:
+			for _, child := range node.Content {
+				walk(child)
+			}
+		case yaml.MappingNode:
+			// Keys are left alone
+			for i := 1; i < len(node.Content); i += 2 {
+				walk(node.Content[i])
+			}
+		case yaml.ScalarNode:
+			if !envReference.MatchString(node.Value) {
+				return
+			}
+			node.Value = envReference.ReplaceAllStringFunc(node.Value, expand)
+			if node.Style&yaml.TaggedStyle == 0 {
+				node.Tag = ""
+				// An empty value is a string, not null
+				if node.ShortTag() == "!!null" {
+					node.Tag = "!!str"
+				}
+			}
+		}
+	}
+	walk(node)
+
+	if len(missing) > 0 {
+		return fmt.Errorf("environment variables referenced in config file are not set: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// readSecretFile reads a secret from a file, ignoring a trailing newline
+func readSecretFile(filename string) (Secret, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return Secret(strings.TrimRight(string(data), "\r\n")), nil
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"net"
+	"strings"
+)
+
+// TargetConfig maps a device name, address or CIDR range to its defaults
+type TargetConfig struct {
+	// Name is matched against the target parameter of a probe. It can be a
+	// device name, an address (with or without port) or a CIDR range.
+	Name string `yaml:"name"`
+
+	// Address is the address used to connect to the device; defaults to the
+	// probed target. Not allowed for CIDR ranges.
+	Address string `yaml:"address,omitempty"`
+
+	Auth   string            `yaml:"auth,omitempty"`
+	Module string            `yaml:"module,omitempty"`
+	Labels map[string]string `yaml:"labels,omitempty"`
+
+	network *net.IPNet
+}
+
+// IsRange reports whether the target is a CIDR range
+func (t TargetConfig) IsRange() bool {
+	return t.network != nil
+}
+
+// init parses CIDR ranges
+func (t *TargetConfig) init() {
+	if strings.Contains(t.Name, "/") {
+		if _, network, err := net.ParseCIDR(t.Name); err == nil {
+			t.network = network
+		}
+	}
+}
+
+// ResolveTarget returns the target configuration for the probed target and
+// the address to connect to. Exact name matches take precedence over CIDR
+// ranges; among ranges the first match in file order wins.
+func (c *Config) ResolveTarget(target string) (TargetConfig, string, bool) {
+	for _, t := range c.Targets {
+		if !t.IsRange() && t.Name == target {
+			address := t.Address
+			if address == "" {
+				address = target
+			}
+			return t, address, true
+		}
+	}
+
+	host := target
+	if h, _, err := net.SplitHostPort(target); err == nil {
+		host = h
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	if ip == nil {
+		return TargetConfig{}, target, false
+	}
+
+	for _, t := range c.Targets {
+		if t.IsRange() && t.network.Contains(ip) {
+			return t, target, true
+		}
+	}
+	return TargetConfig{}, target, false
+}
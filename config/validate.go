@@ -0,0 +1,347 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidationError is a configuration error at a given line of the file
+type ValidationError struct {
+	Line    int
+	Message string
+}
+
+// Error implements the error interface
+func (e ValidationError) Error() string {
+	if e.Line > 0 {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+	}
+	return e.Message
+}
+
+// ValidationErrors is a list of configuration errors
+type ValidationErrors []ValidationError
+
+// Error implements the error interface
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return "invalid configuration:\n  " + strings.Join(msgs, "\n  ")
+}
+
+// sort orders the errors by line number
+func (e ValidationErrors) sort() {
+	sort.SliceStable(e, func(i, j int) bool { return e[i].Line < e[j].Line })
+}
+
+// labelName matches valid Prometheus label names
+var labelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// yamlErrorLine matches the line prefix of yaml.v3 decoding errors
+var yamlErrorLine = regexp.MustCompile(`^line (\d+): (.*)$`)
+
+// parseYAMLError converts a yaml.v3 error message to a ValidationError
+func parseYAMLError(msg string) ValidationError {
+	match := yamlErrorLine.FindStringSubmatch(msg)
+	if match == nil {
+		return ValidationError{Message: msg}
+	}
+	line, _ := strconv.Atoi(match[1])
+	return ValidationError{Line: line, Message: match[2]}
+}
+
+// Validate checks the configuration for references to unknown collectors and
+// other mistakes that YAML decoding cannot catch. Errors make the configuration
+// unusable; warnings are returned for problems that only affect some probes.
+func (c *Config) Validate(collectors Collectors) (warnings []string, err error) {
+	knownCollectors := collectors.List()
+	known := make(map[string]bool)
+	for _, name := range knownCollectors {
+		known[name] = true
+	}
+	available := append([]string(nil), knownCollectors...)
+
+	var errs ValidationErrors
+	addError := func(line int, format string, args ...interface{}) {
+		errs = append(errs, ValidationError{Line: line, Message: fmt.Sprintf(format, args...)})
+	}
+	addWarning := func(line int, format string, args ...interface{}) {
+		warnings = append(warnings, ValidationError{Line: line, Message: fmt.Sprintf(format, args...)}.Error())
+	}
+
+	if len(c.Auths) == 0 {
+		addError(c.line("auths"), "no auth configurations defined")
+	}
+	if len(c.Modules) == 0 {
+		addError(c.line("modules"), "no module configurations defined")
+	}
+
+	for _, name := range sortedKeys(c.Auths) {
+		auth := c.Auths[name]
+		if auth.Username == "" {
+			addWarning(c.line("auths", name), "auth configuration '%s' has no username", name)
+		}
+		if auth.Password == "" {
+			addWarning(c.line("auths", name), "auth configuration '%s' has no password", name)
+		}
+		if auth.TLSConfig != (TLSConfig{}) && auth.Scheme != "https" {
+			addError(c.line("auths", name, "tls_config"), "auth configuration '%s': tls_config is only used with scheme https", name)
+		}
+		if auth.TLSConfig.InsecureSkipVerify {
+			addWarning(c.line("auths", name, "tls_config", "insecure_skip_verify"), "auth configuration '%s' does not verify device certificates", name)
+		}
+	}
+
+	builtin := make(map[string]bool)
+	for name := range known {
+		builtin[name] = true
+	}
+
+	metricNames := make(map[string]string)
+	validCustom := make(map[string]CustomCollectorConfig)
+	for _, name := range sortedKeys(c.CustomCollectors) {
+		custom := c.CustomCollectors[name]
+		if known[name] {
+			addError(c.line("custom_collectors", name), "custom collector '%s': name is used by a built-in collector", name)
+			continue
+		}
+		known[name] = true
+		available = append(available, name)
+		previousErrs := len(errs)
+
+		if !strings.HasPrefix(custom.Path, "/") {
+			addError(c.line("custom_collectors", name, "path"), "custom collector '%s': path must start with '/'", name)
+		}
+		if len(custom.Metrics) == 0 {
+			addError(c.line("custom_collectors", name), "custom collector '%s': no metrics defined", name)
+		}
+		for _, label := range sortedKeys(custom.Labels) {
+			if !labelName.MatchString(label) || strings.HasPrefix(label, "__") {
+				addError(c.line("custom_collectors", name, "labels", label), "custom collector '%s': invalid label name '%s'", name, label)
+			}
+			if custom.Labels[label] == "" {
+				addError(c.line("custom_collectors", name, "labels", label), "custom collector '%s': label '%s' has no property", name, label)
+			}
+		}
+
+		for i, metric := range custom.Metrics {
+			index := strconv.Itoa(i)
+			if !metricName.MatchString(metric.Name) {
+				addError(c.line("custom_collectors", name, "metrics", index, "name"), "custom collector '%s': invalid metric name '%s'", name, metric.Name)
+			} else if other, exists := metricNames[metric.Name]; exists {
+				addError(c.line("custom_collectors", name, "metrics", index, "name"), "custom collector '%s': metric '%s' is already defined by '%s'", name, metric.Name, other)
+			} else {
+				metricNames[metric.Name] = name
+			}
+			if metric.Type != "" && metric.Type != MetricTypeGauge && metric.Type != MetricTypeCounter {
+				addError(c.line("custom_collectors", name, "metrics", index, "type"), "custom collector '%s': metric '%s': unsupported type '%s' (expected gauge or counter)", name, metric.Name, metric.Type)
+			}
+			if !transforms[metric.Transform] {
+				addError(c.line("custom_collectors", name, "metrics", index, "transform"), "custom collector '%s': metric '%s': unsupported transform '%s' (expected bool, duration, bytes or rx_tx)", name, metric.Name, metric.Transform)
+			}
+			if metric.Transform != TransformNone && metric.Property == "" {
+				addError(c.line("custom_collectors", name, "metrics", index), "custom collector '%s': metric '%s': transform requires a property", name, metric.Name)
+			}
+			if _, exists := custom.Labels[DirectionLabel]; exists && metric.Transform == TransformRxTx {
+				addError(c.line("custom_collectors", name, "metrics", index, "transform"), "custom collector '%s': metric '%s': rx_tx conflicts with the '%s' label", name, metric.Name, DirectionLabel)
+			}
+		}
+
+		// The metrics must not reuse the name of a built-in or exporter metric
+		if len(errs) > previousErrs {
+			continue
+		}
+		if err := collectors.ValidateMetrics(map[string]CustomCollectorConfig{name: custom}, nil); err != nil {
+			addError(c.line("custom_collectors", name), "custom collector '%s' %v", name, err)
+			continue
+		}
+		validCustom[name] = custom
+	}
+
+	for _, name := range sortedKeys(c.Modules) {
+		module := c.Modules[name]
+
+		enabled := 0
+		for _, collectorName := range sortedKeys(module.Collectors) {
+			if !known[collectorName] {
+				addError(c.line("modules", name, "collectors", collectorName),
+					"module '%s': unknown collector '%s' (available: %s)", name, collectorName, strings.Join(sortedNames(available), ", "))
+				continue
+			}
+			collectorConfig := module.Collectors[collectorName]
+			if !collectorConfig.Enabled {
+				continue
+			}
+			enabled++
+
+			if len(collectorConfig.Options) == 0 {
+				continue
+			}
+			if !builtin[collectorName] {
+				addError(c.line("modules", name, "collectors", collectorName),
+					"module '%s': custom collector '%s' has no options", name, collectorName)
+				continue
+			}
+			if err := collectors.ValidateOptions(collectorName, collectorConfig.Options); err != nil {
+				addError(c.line("modules", name, "collectors", collectorName),
+					"module '%s': collector '%s': %v", name, collectorName, err)
+			}
+		}
+		if enabled == 0 {
+			addWarning(c.line("modules", name), "module '%s' has no enabled collectors", name)
+		}
+
+		for _, collectorName := range sortedKeys(module.CollectorTimeouts) {
+			if !known[collectorName] {
+				addError(c.line("modules", name, "collector_timeouts", collectorName),
+					"module '%s': timeout for unknown collector '%s'", name, collectorName)
+			}
+			if module.CollectorTimeouts[collectorName] < 0 {
+				addError(c.line("modules", name, "collector_timeouts", collectorName),
+					"module '%s': negative timeout for collector '%s'", name, collectorName)
+			}
+		}
+
+		if module.Concurrency < 0 {
+			addError(c.line("modules", name, "concurrency"), "module '%s': concurrency must not be negative", name)
+		}
+		if module.Timeout < 0 {
+			addError(c.line("modules", name, "timeout"), "module '%s': timeout must not be negative", name)
+		}
+	}
+
+	names := make(map[string]bool)
+	labelErrs := make(map[string]error)
+	for i, target := range c.Targets {
+		index := strconv.Itoa(i)
+		if target.Name == "" {
+			addError(c.line("targets", index), "target %d has no name", i+1)
+			continue
+		}
+		if names[target.Name] {
+			addError(c.line("targets", index, "name"), "duplicate target '%s'", target.Name)
+		}
+		names[target.Name] = true
+
+		if strings.Contains(target.Name, "/") && !target.IsRange() {
+			addError(c.line("targets", index, "name"), "target '%s': invalid CIDR range", target.Name)
+		}
+		if target.IsRange() && target.Address != "" {
+			addError(c.line("targets", index, "address"), "target '%s': address cannot be set for a CIDR range", target.Name)
+		}
+		if _, exists := c.Auths[target.Auth]; target.Auth != "" && !exists {
+			addError(c.line("targets", index, "auth"), "target '%s': unknown auth configuration '%s'", target.Name, target.Auth)
+		}
+		if _, exists := c.Modules[target.Module]; target.Module != "" && !exists {
+			addError(c.line("targets", index, "module"), "target '%s': unknown module configuration '%s'", target.Name, target.Module)
+		}
+		for _, label := range sortedKeys(target.Labels) {
+			if !labelName.MatchString(label) || strings.HasPrefix(label, "__") {
+				addError(c.line("targets", index, "labels", label), "target '%s': invalid label name '%s'", target.Name, label)
+				continue
+			}
+
+			// Target labels are added to every metric of a probe, so they
+			// must not be label names of any collector
+			err, checked := labelErrs[label]
+			if !checked {
+				err = collectors.ValidateMetrics(validCustom, map[string]string{label: target.Labels[label]})
+				labelErrs[label] = err
+			}
+			if err != nil {
+				addError(c.line("targets", index, "labels", label), "target '%s': label '%s' %v", target.Name, label, err)
+			}
+		}
+	}
+
+	for i, rule := range c.Discovery.Rules {
+		index := strconv.Itoa(i)
+		if _, exists := c.Auths[rule.Auth]; rule.Auth != "" && !exists {
+			addError(c.line("discovery", "rules", index, "auth"), "discovery rule %d: unknown auth configuration '%s'", i+1, rule.Auth)
+		}
+		if _, exists := c.Modules[rule.Module]; rule.Module != "" && !exists {
+			addError(c.line("discovery", "rules", index, "module"), "discovery rule %d: unknown module configuration '%s'", i+1, rule.Module)
+		}
+		for _, label := range sortedKeys(rule.Labels) {
+			if !labelName.MatchString(label) || strings.HasPrefix(label, "__") {
+				addError(c.line("discovery", "rules", index, "labels", label), "discovery rule %d: invalid label name '%s'", i+1, label)
+			}
+		}
+	}
+
+	if _, exists := c.Auths["default"]; !exists && len(c.Auths) > 0 {
+		addWarning(c.line("auths"), "no 'default' auth configuration; probes without an auth parameter will fail")
+	}
+	if _, exists := c.Modules["default"]; !exists && len(c.Modules) > 0 {
+		addWarning(c.line("modules"), "no 'default' module configuration; probes without a module parameter will fail")
+	}
+
+	if len(errs) > 0 {
+		errs.sort()
+		return warnings, errs
+	}
+	return warnings, nil
+}
+
+// line returns the line of the key at the given path in the document, or of
+// the closest parent that exists. Sequence items are addressed by index. It
+// returns 0 if the document is empty.
+func (c *Config) line(path ...string) int {
+	node := &c.root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	line := node.Line
+	for _, key := range path {
+		if node.Kind == yaml.SequenceNode {
+			index, err := strconv.Atoi(key)
+			if err != nil || index >= len(node.Content) {
+				return line
+			}
+			node = node.Content[index]
+			line = node.Line
+			continue
+		}
+		if node.Kind != yaml.MappingNode {
+			return line
+		}
+		found := false
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == key {
+				line = node.Content[i].Line
+				node = node.Content[i+1]
+				found = true
+				break
+			}
+		}
+		if !found {
+			return line
+		}
+	}
+	return line
+}
+
+// sortedKeys returns the keys of a map in sorted order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sortedNames returns a sorted copy of names
+func sortedNames(names []string) []string {
+	sorted := append([]string(nil), names...)
+	sort.Strings(sorted)
+	return sorted
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/mikrotik-exporter/collector"
+	"github.com/mikrotik-exporter/routeros"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+)
+
+// debugReport is the response of a debug probe
+type debugReport struct {
+	Target     string                 `json:"target"`
+	Address    string                 `json:"address"`
+	Auth       string                 `json:"auth"`
+	Module     string                 `json:"module"`
+	Duration   string                 `json:"duration"`
+	Collectors []debugCollectorReport `json:"collectors"`
+}
+
+// debugCollectorReport describes the run of a single collector
+type debugCollectorReport struct {
+	Name        string            `json:"name"`
+	Duration    string            `json:"duration"`
+	Error       string            `json:"error,omitempty"`
+	Requests    []debugRequest    `json:"requests"`
+	ParseErrors []debugParseError `json:"parse_errors"`
+	Metrics     []string          `json:"metrics"`
+}
+
+// debugRequest describes a request to the device and its raw response
+type debugRequest struct {
+	Endpoint string          `json:"endpoint"`
+	URL      string          `json:"url"`
+	Status   int             `json:"status"`
+	Duration string          `json:"duration"`
+	Error    string          `json:"error,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+}
+
+// debugParseError is a property that could not be converted to a metric
+type debugParseError struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+	Error string `json:"error"`
+}
+
+// secretFields are masked in raw responses, as are all keys (see isSecretField)
+var secretFields = []string{"password", "passphrase", "secret", "psk"}
+
+// publicFields end in "-key" but are not secret
+var publicFields = []string{"public-key"}
+
+// serveDebugProbe runs the collectors of a probe one after another and
+// reports the requests, raw responses, parse errors and metrics of each.
+// Debug probes are neither coalesced nor cached.
+func serveDebugProbe(w http.ResponseWriter, r *http.Request, req probeRequest) {
+	timeout, err := probeTimeout(r, req.module)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ctx, cancel := context.WithTimeout(r.Context(), timeout)
+	defer cancel()
+
+	client, err := clientPool.Client(req.address, routeros.Auth{
+		Name:      req.authName,
+		Username:  req.auth.Username,
+		Password:  string(req.auth.Password),
+		Transport: req.auth.Transport,
+		Scheme:    req.auth.Scheme,
+		TLS:       req.auth.TLS(),
+	})
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Auth configuration error: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	report := debugReport{
+		Target:  req.target,
+		Address: req.address,
+		Auth:    req.authName,
+		Module:  req.moduleName,
+	}
+
+	start := time.Now()
+	for _, c := range req.collectors {
+		report.Collectors = append(report.Collectors, debugCollector(ctx, client, req, c))
+	}
+	report.Duration = time.Since(start).String()
+
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		req.logger.Error("Error writing debug report", "err", err)
+	}
+}
+
+// debugCollector runs one collector of a debug probe and reports its
+// requests, parse errors and metrics
+func debugCollector(ctx context.Context, client *routeros.Client, req probeRequest, c collector.Collector) debugCollectorReport {
+	logger := req.logger.With("collector", c.Name(), "debug", true)
+	trace := &routeros.Trace{}
+	collectorClient := client.WithLogger(logger).WithTrace(trace)
+
+	collectorCtx := ctx
+	if timeout := req.module.GetCollectorTimeout(c.Name()); timeout > 0 {
+		var cancel context.CancelFunc
+		collectorCtx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Collect into a buffer so the metrics can be listed
+	ch := make(chan prometheus.Metric)
+	done := make(chan []prometheus.Metric)
+	go func() {
+		var metrics []prometheus.Metric
+		for m := range ch {
+			metrics = append(metrics, m)
+		}
+		done <- metrics
+	}()
+
+	// Debug probes count against the concurrency limit of the target too
+	collectErr := targetSlots.acquire(ctx, req.address, req.module.GetConcurrency())
+	collectorStart := time.Now()
+	if collectErr == nil {
+		collectErr = c.Collect(collectorCtx, collectorClient, ch)
+		targetSlots.release(req.address)
+	}
+	close(ch)
+	metrics := <-done
+
+	collectorReport := debugCollectorReport{
+		Name:        c.Name(),
+		Duration:    time.Since(collectorStart).String(),
+		Requests:    []debugRequest{},
+		ParseErrors: []debugParseError{},
+		Metrics:     formatMetrics(logger, req.labels, metrics),
+	}
+	if collectErr != nil {
+		collectorReport.Error = collectErr.Error()
+	}
+
+	for _, traced := range trace.Requests() {
+		request := debugRequest{
+			Endpoint: traced.Endpoint,
+			URL:      traced.URL,
+			Status:   traced.StatusCode,
+			Duration: traced.Duration.String(),
+			Response: maskSecrets(traced.Body),
+		}
+		if traced.Err != nil {
+			request.Error = traced.Err.Error()
+		}
+		collectorReport.Requests = append(collectorReport.Requests, request)
+	}
+
+	for _, parseErr := range trace.ParseErrors() {
+		collectorReport.ParseErrors = append(collectorReport.ParseErrors, debugParseError{
+			Field: parseErr.Field,
+			Value: parseErr.Value,
+			Error: parseErr.Err.Error(),
+		})
+	}
+
+	return collectorReport
+}
+
+// formatMetrics renders metrics in the text exposition format, one line per sample
+func formatMetrics(logger *slog.Logger, labels map[string]string, metrics []prometheus.Metric) []string {
+	registry := prometheus.NewRegistry()
+	if err := prometheus.WrapRegistererWith(labels, registry).Register(metricList(metrics)); err != nil {
+		logger.Error("Error registering debug metrics", "err", err)
+		return []string{}
+	}
+	families, err := registry.Gather()
+	if err != nil {
+		// Invalid metrics are worth reporting in a debug probe
+		logger.Error("Error gathering debug metrics", "err", err)
+	}
+
+	var buf bytes.Buffer
+	for _, mf := range families {
+		expfmt.MetricFamilyToText(&buf, mf)
+	}
+
+	lines := []string{}
+	for _, line := range strings.Split(buf.String(), "\n") {
+		if line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// metricList is an unchecked collector returning a fixed list of metrics
+type metricList []prometheus.Metric
+
+func (l metricList) Describe(ch chan<- *prometheus.Desc) {}
+
+func (l metricList) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range l {
+		ch <- m
+	}
+}
+
+// maskSecrets replaces the values of secret properties in a JSON response
+func maskSecrets(body []byte) json.RawMessage {
+	if len(body) == 0 {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		// Not JSON; return it as a string so the report stays valid
+		raw, _ := json.Marshal(string(body))
+		return raw
+	}
+
+	masked, err := json.Marshal(maskValue(value))
+	if err != nil {
+		return nil
+	}
+	return masked
+}
+
+// maskValue walks a decoded JSON value and masks secret properties
+func maskValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if isSecretField(key) {
+				v[key] = "<secret>"
+				continue
+			}
+			v[key] = maskValue(item)
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = maskValue(item)
+		}
+	}
+	return value
+}
+
+// isSecretField reports whether a property holds a secret. Besides the
+// secretFields, every key is a secret (private-key, pre-shared-key, and the
+// auth-key and enc-key of IPsec SAs) except the public ones.
+func isSecretField(key string) bool {
+	key = strings.ToLower(key)
+	if key == "key" || strings.HasSuffix(key, "-key") {
+		return !slices.Contains(publicFields, key)
+	}
+	for _, field := range secretFields {
+		if strings.Contains(key, field) {
+			return true
+		}
+	}
+	return false
+}
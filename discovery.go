@@ -0,0 +1,49 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/mikrotik-exporter/discovery"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// inventory holds the devices found by MNDP discovery; nil if discovery is disabled
+var inventory *discovery.Inventory
+
+// startDiscovery listens for MNDP announcements on the given address and
+// registers the discovery metrics
+func startDiscovery(address string, maxAge time.Duration, registry prometheus.Registerer) error {
+	inv := discovery.NewInventory(maxAge)
+	listener, err := discovery.NewListener(address, inv)
+	if err != nil {
+		return err
+	}
+	inventory = inv
+
+	registry.MustRegister(
+		prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Name: metricsNamespace + "_mndp_devices",
+			Help: "Number of devices in the MNDP discovery inventory",
+		}, func() float64 { return float64(len(inv.Devices())) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: metricsNamespace + "_mndp_packets_total",
+			Help: "Total number of MNDP announcements received",
+		}, func() float64 { return float64(listener.Packets.Load()) }),
+		prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Name: metricsNamespace + "_mndp_invalid_packets_total",
+			Help: "Total number of MNDP announcements that could not be parsed",
+		}, func() float64 { return float64(listener.Invalid.Load()) }),
+	)
+
+	go func() {
+		err := listener.Serve(func(source net.Addr, err error) {
+			slog.Debug("Invalid MNDP packet", "source", source.String(), "err", err)
+		})
+		if err != nil {
+			slog.Error("MNDP listener stopped", "err", err)
+		}
+	}()
+	return nil
+}
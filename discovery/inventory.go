@@ -0,0 +1,127 @@
+package discovery
+
+import (
+	"errors"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Inventory keeps the devices seen by MNDP. Devices that have not announced
+// themselves within the maximum age are dropped.
+type Inventory struct {
+	maxAge time.Duration
+
+	// now returns the current time; replaced in tests
+	now func() time.Time
+
+	mu      sync.Mutex
+	devices map[string]Device
+}
+
+// NewInventory creates an empty inventory
+func NewInventory(maxAge time.Duration) *Inventory {
+	return &Inventory{
+		maxAge:  maxAge,
+		now:     time.Now,
+		devices: make(map[string]Device),
+	}
+}
+
+// Update adds a device or refreshes its information and last-seen time
+func (i *Inventory) Update(device Device) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.devices[device.Key()] = device
+}
+
+// Devices returns the devices seen within the maximum age, ordered by address
+func (i *Inventory) Devices() []Device {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	now := i.now()
+	devices := make([]Device, 0, len(i.devices))
+	for key, device := range i.devices {
+		if i.maxAge > 0 && now.Sub(device.LastSeen) > i.maxAge {
+			delete(i.devices, key)
+			continue
+		}
+		devices = append(devices, device)
+	}
+
+	sort.Slice(devices, func(a, b int) bool {
+		if devices[a].Address() != devices[b].Address() {
+			return devices[a].Address() < devices[b].Address()
+		}
+		return devices[a].Key() < devices[b].Key()
+	})
+	return devices
+}
+
+// maxPacketSize is large enough for any MNDP announcement
+const maxPacketSize = 1500
+
+// Listener receives MNDP announcements and records them in an inventory
+type Listener struct {
+	conn      net.PacketConn
+	inventory *Inventory
+
+	// Packets and Invalid count the received and unparseable announcements
+	Packets atomic.Uint64
+	Invalid atomic.Uint64
+}
+
+// NewListener creates a listener on the given UDP address, e.g. ":5678"
+func NewListener(address string, inventory *Inventory) (*Listener, error) {
+	conn, err := net.ListenPacket("udp4", address)
+	if err != nil {
+		return nil, err
+	}
+	return &Listener{
+		conn:      conn,
+		inventory: inventory,
+	}, nil
+}
+
+// Serve reads announcements until the listener is closed. Invalid packets
+// are passed to logInvalid and otherwise ignored.
+func (l *Listener) Serve(logInvalid func(source net.Addr, err error)) error {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, source, err := l.conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		device, err := ParsePacket(buf[:n])
+		if errors.Is(err, ErrRequest) {
+			continue
+		}
+		l.Packets.Add(1)
+		if err != nil {
+			l.Invalid.Add(1)
+			if logInvalid != nil {
+				logInvalid(source, err)
+			}
+			continue
+		}
+
+		if udpAddr, ok := source.(*net.UDPAddr); ok {
+			device.Source = udpAddr.IP
+		}
+		device.LastSeen = l.inventory.now()
+		l.inventory.Update(device)
+	}
+}
+
+// Close stops the listener
+func (l *Listener) Close() error {
+	return l.conn.Close()
+}
@@ -0,0 +1,155 @@
+package discovery
+
+import (
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a settable time source for the inventory
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newTestInventory(maxAge time.Duration) (*Inventory, *fakeClock) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	inventory := NewInventory(maxAge)
+	inventory.now = clock.Now
+	return inventory, clock
+}
+
+func TestInventoryExpiry(t *testing.T) {
+	inventory, clock := newTestInventory(time.Minute)
+
+	inventory.Update(Device{SoftwareID: "A", IPv4: net.ParseIP("10.0.0.2"), LastSeen: clock.Now()})
+	clock.Advance(30 * time.Second)
+	inventory.Update(Device{SoftwareID: "B", IPv4: net.ParseIP("10.0.0.1"), LastSeen: clock.Now()})
+
+	devices := inventory.Devices()
+	if len(devices) != 2 || devices[0].Key() != "B" || devices[1].Key() != "A" {
+		t.Fatalf("Devices() = %+v, want B and A ordered by address", devices)
+	}
+
+	// A is dropped once it has not announced itself for longer than the maximum age
+	clock.Advance(31 * time.Second)
+	devices = inventory.Devices()
+	if len(devices) != 1 || devices[0].Key() != "B" {
+		t.Fatalf("Devices() = %+v, want only B", devices)
+	}
+
+	// A new announcement brings it back
+	inventory.Update(Device{SoftwareID: "A", IPv4: net.ParseIP("10.0.0.2"), LastSeen: clock.Now()})
+	if devices = inventory.Devices(); len(devices) != 2 {
+		t.Fatalf("Devices() = %+v, want A and B", devices)
+	}
+}
+
+func TestListener(t *testing.T) {
+	inventory, clock := newTestInventory(time.Minute)
+	listener, err := NewListener("127.0.0.1:0", inventory)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var invalid []error
+	done := make(chan error)
+	go func() {
+		done <- listener.Serve(func(source net.Addr, err error) {
+			mu.Lock()
+			defer mu.Unlock()
+			invalid = append(invalid, err)
+		})
+	}()
+	defer func() {
+		listener.Close()
+		if err := <-done; err != nil {
+			t.Errorf("Serve() error = %v", err)
+		}
+	}()
+
+	conn, err := net.Dial("udp4", listener.conn.LocalAddr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	routeros7 := readFixture(t, "routeros7.hex")
+	routeros6 := readFixture(t, "routeros6.hex")
+	replay := func(packets ...[]byte) {
+		t.Helper()
+		want := listener.Packets.Load()
+		for _, packet := range packets {
+			if _, err := conn.Write(packet); err != nil {
+				t.Fatal(err)
+			}
+			if len(packet) != mndpHeaderSize {
+				want++
+			}
+		}
+		// Requests are not counted, so wait for the announcements only
+		deadline := time.Now().Add(5 * time.Second)
+		for listener.Packets.Load() < want {
+			if time.Now().After(deadline) {
+				t.Fatalf("received %d packets, want %d", listener.Packets.Load(), want)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	replay(
+		readFixture(t, "request.hex"),
+		routeros7,
+		routeros6,
+		routeros7[:len(routeros7)-3],
+	)
+
+	if n := listener.Invalid.Load(); n != 1 {
+		t.Errorf("Invalid = %d, want 1", n)
+	}
+	mu.Lock()
+	if len(invalid) != 1 {
+		t.Errorf("logged invalid packets = %v, want the truncated one", invalid)
+	}
+	mu.Unlock()
+
+	devices := inventory.Devices()
+	if len(devices) != 2 {
+		t.Fatalf("Devices() = %+v, want 2 devices", devices)
+	}
+	// The RouterOS 6 device has no IPv4 TLV and is probed at the source address
+	addresses := map[string]string{}
+	for _, device := range devices {
+		addresses[device.Identity] = device.Address()
+		if !device.LastSeen.Equal(clock.Now()) {
+			t.Errorf("device %s LastSeen = %v, want %v", device.Identity, device.LastSeen, clock.Now())
+		}
+	}
+	if addresses["core-rtr-1"] != "10.0.0.1" || addresses["cpe-lobby"] != "127.0.0.1" {
+		t.Errorf("addresses = %v, want core-rtr-1 at 10.0.0.1 and cpe-lobby at 127.0.0.1", addresses)
+	}
+
+	// Only the RouterOS 7 device keeps announcing; the other one expires
+	clock.Advance(45 * time.Second)
+	replay(routeros7)
+	clock.Advance(30 * time.Second)
+
+	devices = inventory.Devices()
+	if len(devices) != 1 || devices[0].Identity != "core-rtr-1" {
+		t.Fatalf("Devices() = %+v, want only core-rtr-1", devices)
+	}
+}
@@ -0,0 +1,142 @@
+package discovery
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// DefaultPort is the UDP port MNDP announcements are sent to
+const DefaultPort = 5678
+
+// MNDP TLV types
+const (
+	tlvMAC        = 1
+	tlvIdentity   = 5
+	tlvVersion    = 7
+	tlvPlatform   = 8
+	tlvUptime     = 10
+	tlvSoftwareID = 11
+	tlvBoard      = 12
+	tlvUnpack     = 14
+	tlvIPv6       = 15
+	tlvInterface  = 16
+	tlvIPv4       = 17
+)
+
+// mndpHeaderSize is the size of the header preceding the TLVs
+const mndpHeaderSize = 4
+
+// ErrRequest is returned by ParsePacket for discovery requests, which carry no device information
+var ErrRequest = errors.New("MNDP discovery request")
+
+// Device is a MikroTik device announced by MNDP
+type Device struct {
+	MAC        string
+	Identity   string
+	Version    string
+	Platform   string
+	Uptime     time.Duration
+	SoftwareID string
+	Board      string
+	Unpack     uint8
+	IPv6       net.IP
+	Interface  string
+	IPv4       net.IP
+
+	// Source is the address the announcement was received from
+	Source net.IP
+
+	// LastSeen is the time of the last announcement
+	LastSeen time.Time
+}
+
+// Key identifies a device across announcements. Devices announce on every
+// interface, so the software ID is preferred over the interface MAC.
+func (d Device) Key() string {
+	if d.SoftwareID != "" {
+		return d.SoftwareID
+	}
+	return d.MAC
+}
+
+// Address returns the address used to probe the device: the announced IPv4
+// address, or the source address of the announcement
+func (d Device) Address() string {
+	if d.IPv4 != nil && !d.IPv4.IsUnspecified() {
+		return d.IPv4.String()
+	}
+	if d.Source != nil {
+		return d.Source.String()
+	}
+	return ""
+}
+
+// ParsePacket parses an MNDP announcement. Unknown TLVs are ignored.
+func ParsePacket(data []byte) (Device, error) {
+	var device Device
+
+	if len(data) < mndpHeaderSize {
+		return device, fmt.Errorf("packet too short (%d bytes)", len(data))
+	}
+	if len(data) == mndpHeaderSize {
+		return device, ErrRequest
+	}
+
+	data = data[mndpHeaderSize:]
+	for len(data) > 0 {
+		if len(data) < 4 {
+			return device, fmt.Errorf("truncated TLV header")
+		}
+		tlvType := binary.BigEndian.Uint16(data[0:2])
+		length := int(binary.BigEndian.Uint16(data[2:4]))
+		if len(data) < 4+length {
+			return device, fmt.Errorf("truncated TLV %d (need %d bytes, have %d)", tlvType, length, len(data)-4)
+		}
+		value := data[4 : 4+length]
+		data = data[4+length:]
+
+		switch tlvType {
+		case tlvMAC:
+			if length == 6 {
+				device.MAC = net.HardwareAddr(value).String()
+			}
+		case tlvIdentity:
+			device.Identity = string(value)
+		case tlvVersion:
+			device.Version = string(value)
+		case tlvPlatform:
+			device.Platform = string(value)
+		case tlvUptime:
+			// Uptime is the only little-endian value
+			if length == 4 {
+				device.Uptime = time.Duration(binary.LittleEndian.Uint32(value)) * time.Second
+			}
+		case tlvSoftwareID:
+			device.SoftwareID = string(value)
+		case tlvBoard:
+			device.Board = string(value)
+		case tlvUnpack:
+			if length == 1 {
+				device.Unpack = value[0]
+			}
+		case tlvIPv6:
+			if length == net.IPv6len {
+				device.IPv6 = net.IP(append([]byte(nil), value...))
+			}
+		case tlvInterface:
+			device.Interface = string(value)
+		case tlvIPv4:
+			if length == net.IPv4len {
+				device.IPv4 = net.IPv4(value[0], value[1], value[2], value[3])
+			}
+		}
+	}
+
+	if device.MAC == "" && device.SoftwareID == "" {
+		return device, fmt.Errorf("announcement without MAC address or software ID")
+	}
+	return device, nil
+}
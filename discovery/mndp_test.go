@@ -0,0 +1,165 @@
+package discovery
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readFixture reads a packet from a hex dump in testdata; lines starting
+// with '#' are comments
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	file, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var digits strings.Builder
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		digits.WriteString(strings.ReplaceAll(line, " ", ""))
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := hex.DecodeString(digits.String())
+	if err != nil {
+		t.Fatalf("invalid fixture %s: %v", name, err)
+	}
+	return data
+}
+
+func TestParsePacket(t *testing.T) {
+	tests := []struct {
+		fixture string
+		want    Device
+	}{
+		{
+			fixture: "routeros7.hex",
+			want: Device{
+				MAC:        "48:a9:8a:0c:1d:2e",
+				Identity:   "core-rtr-1",
+				Version:    "7.12.1 (stable)",
+				Platform:   "MikroTik",
+				Uptime:     1234567 * time.Second,
+				SoftwareID: "ABCD-EFGH",
+				Board:      "CCR2004-1G-12S+2XS",
+				Unpack:     0,
+				IPv6:       net.ParseIP("fe80::4aa9:8aff:fe0c:1d2e"),
+				Interface:  "ether1",
+				IPv4:       net.ParseIP("10.0.0.1"),
+			},
+		},
+		{
+			fixture: "routeros6.hex",
+			want: Device{
+				MAC:        "c4:ad:34:a1:b2:c3",
+				Identity:   "cpe-lobby",
+				Version:    "6.49.10 (long-term)",
+				Platform:   "MikroTik",
+				Uptime:     24 * time.Hour,
+				SoftwareID: "WXYZ-1234",
+				Board:      "RBD52G-5HacD2HnD",
+				Unpack:     1,
+				Interface:  "bridge",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			device, err := ParsePacket(readFixture(t, tt.fixture))
+			if err != nil {
+				t.Fatalf("ParsePacket() error = %v", err)
+			}
+
+			if device.MAC != tt.want.MAC || device.Identity != tt.want.Identity ||
+				device.Version != tt.want.Version || device.Platform != tt.want.Platform ||
+				device.Uptime != tt.want.Uptime || device.SoftwareID != tt.want.SoftwareID ||
+				device.Board != tt.want.Board || device.Unpack != tt.want.Unpack ||
+				device.Interface != tt.want.Interface {
+				t.Errorf("ParsePacket() = %+v, want %+v", device, tt.want)
+			}
+			if !device.IPv4.Equal(tt.want.IPv4) || !device.IPv6.Equal(tt.want.IPv6) {
+				t.Errorf("ParsePacket() addresses = %v, %v, want %v, %v", device.IPv4, device.IPv6, tt.want.IPv4, tt.want.IPv6)
+			}
+		})
+	}
+}
+
+func TestParsePacketRequest(t *testing.T) {
+	if _, err := ParsePacket(readFixture(t, "request.hex")); !errors.Is(err, ErrRequest) {
+		t.Errorf("ParsePacket(request) error = %v, want %v", err, ErrRequest)
+	}
+
+	// An empty datagram is not a request, just too short
+	_, err := ParsePacket(nil)
+	if err == nil || errors.Is(err, ErrRequest) {
+		t.Errorf("ParsePacket(empty) error = %v, want packet too short", err)
+	}
+}
+
+func TestParsePacketTruncated(t *testing.T) {
+	packet := readFixture(t, "routeros7.hex")
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{
+			name:    "short header",
+			data:    packet[:3],
+			wantErr: "packet too short (3 bytes)",
+		},
+		{
+			name:    "truncated TLV header",
+			data:    packet[:mndpHeaderSize+2],
+			wantErr: "truncated TLV header",
+		},
+		{
+			name:    "truncated MAC value",
+			data:    packet[:mndpHeaderSize+4+3],
+			wantErr: "truncated TLV 1 (need 6 bytes, have 3)",
+		},
+		{
+			name:    "length beyond packet",
+			data:    append(append([]byte(nil), packet...), 0x00, 0x05, 0x01, 0x00, 'x'),
+			wantErr: "truncated TLV 5 (need 256 bytes, have 1)",
+		},
+		{
+			name:    "no MAC or software ID",
+			data:    []byte{0, 0, 0, 1, 0x00, 0x05, 0x00, 0x01, 'x'},
+			wantErr: "announcement without MAC address or software ID",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePacket(tt.data)
+			if err == nil || err.Error() != tt.wantErr {
+				t.Errorf("ParsePacket() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Cutting the packet anywhere must fail cleanly or yield the TLVs before the cut
+	for n := range len(packet) {
+		if _, err := ParsePacket(packet[:n]); err == nil && n < mndpHeaderSize+4+6 {
+			t.Errorf("ParsePacket(%d bytes) succeeded without a MAC address", n)
+		}
+	}
+}
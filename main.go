@@ -3,43 +3,161 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"html/template"
+	"log/slog"
 	"net/http"
 	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/mikrotik-exporter/collector"
+	"github.com/mikrotik-exporter/collector/bfd"
 	"github.com/mikrotik-exporter/collector/bgp"
+	"github.com/mikrotik-exporter/collector/custom"
 	"github.com/mikrotik-exporter/collector/dhcp"
 	"github.com/mikrotik-exporter/collector/firewall"
 	"github.com/mikrotik-exporter/collector/interfaces"
+	"github.com/mikrotik-exporter/collector/ipsec"
+	"github.com/mikrotik-exporter/collector/ospf"
+	"github.com/mikrotik-exporter/collector/routing"
 	"github.com/mikrotik-exporter/collector/system"
+	"github.com/mikrotik-exporter/collector/wireguard"
 	"github.com/mikrotik-exporter/collector/wireless"
 	"github.com/mikrotik-exporter/config"
+	"github.com/mikrotik-exporter/routeros"
+	"github.com/mikrotik-exporter/web"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
-	"github.com/prometheus/common/expfmt"
 )
 
 var (
-	cfg               *config.Config
+	cfg               atomic.Pointer[loadedConfig]
 	collectorRegistry *collector.Registry
+	clientPool        *routeros.Pool
 	metricsNamespace  string
+	timeoutOffset     time.Duration
+
+	// webConfig protects the exporter's own listener; empty unless WEB_CONFIG_FILE is set
+	webConfig = &web.Config{}
+
+	// authenticator checks the basic auth users of the web configuration
+	authenticator *web.Authenticator
 )
 
+// defaultProbeTimeout is used when neither Prometheus nor the module sets a timeout
+const defaultProbeTimeout = 30 * time.Second
+
 func init() {
 	// Initialize collector registry - collectors will be registered in main() with namespace
 	collectorRegistry = collector.NewRegistry()
+
+	// Connections to devices are shared between probes through the pool
+	clientPool = routeros.NewPool()
 }
 
 func main() {
+	// "check-config" validates the configuration file and exits
+	if len(os.Args) > 1 && os.Args[1] == "check-config" {
+		os.Exit(checkConfig(os.Args[2:]))
+	}
+
 	// Get configuration from environment variables
 	listenAddr := getEnv("LISTEN_ADDR", "0.0.0.0")
 	listenPort := getEnv("LISTEN_PORT", "9261")
 	configFile := getEnv("CONFIG_FILE", "./config.yaml")
 	metricsNamespace = getEnv("METRICS_NAMESPACE", "mikrotik_exporter")
 
-	// Register collectors with namespace
+	logger, err := newLogger(os.Stderr, getEnv("LOG_LEVEL", "info"), getEnv("LOG_FORMAT", "logfmt"))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	slog.SetDefault(logger)
+
+	timeoutOffset, err = time.ParseDuration(getEnv("TIMEOUT_OFFSET", "500ms"))
+	if err != nil {
+		fatal("Invalid TIMEOUT_OFFSET", "err", err)
+	}
+
+	if webConfigFile := getEnv("WEB_CONFIG_FILE", ""); webConfigFile != "" {
+		webConfig, err = web.LoadConfig(webConfigFile)
+		if err != nil {
+			fatal("Failed to load web configuration", "file", webConfigFile, "err", err)
+		}
+	}
+
+	authenticator = web.NewAuthenticator(webConfig)
+
+	registerCollectors()
+
+	// Setup metrics with default Go metrics
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(prometheus.NewGoCollector())
+	registry.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
+
+	// Exporter metrics are updated by probes and by every request to a device
+	selfMetrics = newExporterMetrics(registry)
+	clientPool.SetRequestHook(selfMetrics.observeRequest)
+
+	// Load configuration; it is reloaded on SIGHUP and POST /-/reload
+	reloader := NewConfigReloader(configFile, registry)
+	if err := reloader.Reload(); err != nil {
+		fatal("Failed to load configuration", "file", configFile, "err", err)
+	}
+	reloader.WatchSignals()
+
+	// Background polling is optional; /probe then serves the cached results
+	if pollInterval := getEnv("POLL_INTERVAL", ""); pollInterval != "" {
+		interval, err := time.ParseDuration(pollInterval)
+		if err != nil || interval <= 0 {
+			fatal("Invalid POLL_INTERVAL", "value", pollInterval)
+		}
+		maxAge, err := time.ParseDuration(getEnv("POLL_MAX_AGE", (3 * interval).String()))
+		if err != nil {
+			fatal("Invalid POLL_MAX_AGE", "err", err)
+		}
+		poller = NewPoller(interval, maxAge)
+		go poller.Run()
+		slog.Info("Polling configured targets", "interval", interval, "max_age", maxAge)
+	}
+
+	// MNDP discovery is optional; discovered devices are served through /sd
+	if mndpAddr := getEnv("MNDP_LISTEN_ADDR", ""); mndpAddr != "" {
+		mndpMaxAge, err := time.ParseDuration(getEnv("MNDP_MAX_AGE", "10m"))
+		if err != nil {
+			fatal("Invalid MNDP_MAX_AGE", "err", err)
+		}
+		if err := startDiscovery(mndpAddr, mndpMaxAge, registry); err != nil {
+			fatal("Failed to start MNDP discovery", "err", err)
+		}
+		slog.Info("Listening for MNDP announcements", "address", mndpAddr)
+	}
+
+	// Setup HTTP handlers
+	http.HandleFunc("/probe", probeHandler)
+	http.HandleFunc("/sd", sdHandler)
+	http.HandleFunc("/health-check", healthCheckHandler)
+	http.Handle("/-/reload", reloader)
+	http.HandleFunc("/", indexHandler)
+	http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+
+	// Start HTTP server
+	addr := fmt.Sprintf("%s:%s", listenAddr, listenPort)
+	slog.Info("Starting Mikrotik Prometheus Exporter", "address", addr, "collectors", collectorRegistry.List())
+
+	// Everything but the health check requires credentials if users are configured
+	handler := authenticator.Protect(http.DefaultServeMux, "/health-check")
+	if err := web.ListenAndServe(addr, handler, webConfig); err != nil {
+		fatal("Failed to start HTTP server", "err", err)
+	}
+}
+
+// registerCollectors registers all collectors with the current namespace
+func registerCollectors() {
 	interfacesCollector := interfaces.NewCollector()
 	interfacesCollector.SetNamespace(metricsNamespace)
 	collectorRegistry.Register(interfacesCollector)
@@ -64,32 +182,94 @@ func main() {
 	firewallCollector.SetNamespace(metricsNamespace)
 	collectorRegistry.Register(firewallCollector)
 
-	// Load configuration
-	var err error
-	cfg, err = config.LoadConfig(configFile)
-	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+	routingCollector := routing.NewCollector()
+	routingCollector.SetNamespace(metricsNamespace)
+	collectorRegistry.Register(routingCollector)
+
+	ospfCollector := ospf.NewCollector()
+	ospfCollector.SetNamespace(metricsNamespace)
+	collectorRegistry.Register(ospfCollector)
+
+	bfdCollector := bfd.NewCollector()
+	bfdCollector.SetNamespace(metricsNamespace)
+	collectorRegistry.Register(bfdCollector)
+
+	wireguardCollector := wireguard.NewCollector()
+	wireguardCollector.SetNamespace(metricsNamespace)
+	collectorRegistry.Register(wireguardCollector)
+
+	ipsecCollector := ipsec.NewCollector()
+	ipsecCollector.SetNamespace(metricsNamespace)
+	collectorRegistry.Register(ipsecCollector)
+}
+
+// collectorCatalog describes the collectors to the configuration: the
+// built-in collectors of the registry, custom collectors and the metrics the
+// exporter adds to every probe
+type collectorCatalog struct {
+	*collector.Registry
+}
+
+// describedCollectors registers collectors by their descriptors only
+type describedCollectors []collector.Collector
+
+func (d describedCollectors) Describe(ch chan<- *prometheus.Desc) {
+	for _, c := range d {
+		c.Describe(ch)
 	}
+}
 
-	// Setup HTTP handlers
-	http.HandleFunc("/probe", probeHandler)
-	http.HandleFunc("/health-check", healthCheckHandler)
-	http.HandleFunc("/", indexHandler)
+func (d describedCollectors) Collect(ch chan<- prometheus.Metric) {}
 
-	// Setup metrics with default Go metrics
-	registry := prometheus.NewRegistry()
-	registry.MustRegister(prometheus.NewGoCollector())
-	registry.MustRegister(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}))
-	http.Handle("/metrics", promhttp.HandlerFor(registry, promhttp.HandlerOpts{}))
+// conflictingMetric matches the metric name in registration errors
+var conflictingMetric = regexp.MustCompile(`fqName: "([^"]+)"`)
 
-	// Start HTTP server
-	addr := fmt.Sprintf("%s:%s", listenAddr, listenPort)
-	log.Printf("Starting Mikrotik Prometheus Exporter on %s", addr)
-	log.Printf("Available collectors: %v", collectorRegistry.List())
+// metricConflict shortens a registration error to the conflicting metric
+func metricConflict(err error) error {
+	if match := conflictingMetric.FindStringSubmatch(err.Error()); match != nil {
+		return fmt.Errorf("conflicts with metric '%s'", match[1])
+	}
+	return fmt.Errorf("conflicts with other metrics: %w", err)
+}
+
+// ValidateMetrics registers everything a probe could register in an empty
+// registry. Custom collectors are registered on their own, so that reusing
+// the name of any other metric is an error even if the labels match.
+func (c collectorCatalog) ValidateMetrics(customCollectors map[string]config.CustomCollectorConfig, labels map[string]string) error {
+	registerer := prometheus.WrapRegistererWith(labels, prometheus.NewRegistry())
 
-	if err := http.ListenAndServe(addr, nil); err != nil {
-		log.Fatalf("Failed to start HTTP server: %v", err)
+	if err := registerer.Register(newProbeCollector(context.Background(), nil, probeRequest{})); err != nil {
+		return metricConflict(err)
+	}
+	if err := registerer.Register(newLastScrapeGauge()); err != nil {
+		return metricConflict(err)
+	}
+
+	var builtins describedCollectors
+	for _, name := range c.List() {
+		builtin, err := c.Get(name)
+		if err != nil {
+			return err
+		}
+		builtins = append(builtins, builtin)
+	}
+	if err := registerer.Register(builtins); err != nil {
+		return metricConflict(err)
+	}
+
+	names := make([]string, 0, len(customCollectors))
+	for name := range customCollectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		customCollector := custom.NewCollector(name, customCollectors[name])
+		customCollector.SetNamespace(metricsNamespace)
+		if err := registerer.Register(describedCollectors{customCollector}); err != nil {
+			return metricConflict(err)
+		}
 	}
+	return nil
 }
 
 func probeHandler(w http.ResponseWriter, r *http.Request) {
@@ -102,88 +282,78 @@ func probeHandler(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Missing 'target' parameter", http.StatusBadRequest)
 		return
 	}
-	if authName == "" {
-		authName = "default"
-	}
-	if moduleName == "" {
-		moduleName = "default"
-	}
 
-	// Get authentication configuration
-	authConfig, err := cfg.GetAuth(authName)
+	// Use the same configuration for the whole probe even if it is reloaded meanwhile
+	req, err := newProbeRequest(cfg.Load(), target, authName, moduleName)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Auth configuration error: %v", err), http.StatusBadRequest)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Get module configuration
-	moduleConfig, err := cfg.GetModule(moduleName)
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Module configuration error: %v", err), http.StatusBadRequest)
+	// Never connect to devices outside of the allow-list of the web configuration
+	if !webConfig.AllowsTarget(req.address) {
+		http.Error(w, fmt.Sprintf("Target '%s' is not allowed", target), http.StatusForbidden)
 		return
 	}
 
-	// Create a custom registry for this probe
-	registry := prometheus.NewRegistry()
-
-	// Get enabled collectors
-	enabledCollectors := collectorRegistry.GetEnabled(moduleConfig.Collectors)
-	if len(enabledCollectors) == 0 {
-		http.Error(w, "No collectors enabled for this module", http.StatusBadRequest)
+	// Debug probes expose raw device responses, so only admins may run them
+	if r.URL.Query().Get("debug") == "true" {
+		if user, ok := authenticator.Authenticate(r); !ok || !webConfig.IsAdmin(user) {
+			http.Error(w, "Debug probes require an admin user (admin_users in the web configuration)", http.StatusForbidden)
+			return
+		}
+		serveDebugProbe(w, r, req)
 		return
 	}
 
-	// Create a custom collector that will run all enabled collectors
-	probeCollector := &ProbeCollector{
-		target:     target,
-		auth:       collector.AuthInfo{Username: authConfig.Username, Password: authConfig.Password},
-		collectors: enabledCollectors,
-		collectorSuccess: prometheus.NewDesc(
-			metricsNamespace+"_collector_success",
-			"Whether a collector succeeded (1) or failed (0)",
-			[]string{"collector"},
-			nil,
-		),
+	// Serve the result of background polling if the target is polled
+	if poller != nil {
+		if result, ok := poller.Get(req.key()); ok {
+			serveProbe(w, req, result, poller.IsStale(result))
+			return
+		}
 	}
 
-	registry.MustRegister(probeCollector)
-
-	// Create a timeout context
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
-
-	// Store context in the collector for use during collection
-	probeCollector.ctx = ctx
-
-	// Create a custom gatherer to check for errors
-	gatherer := registry
-	metricFamilies, err := gatherer.Gather()
+	// Create a timeout bounded by the Prometheus scrape timeout
+	timeout, err := probeTimeout(r, req.module)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error gathering metrics: %v", err), 499)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	// Check if any collectors had errors
-	if len(probeCollector.errors) > 0 {
-		errorMsg := "Collector errors: "
-		for i, collectorErr := range probeCollector.errors {
-			if i > 0 {
-				errorMsg += "; "
-			}
-			errorMsg += collectorErr.Error()
-		}
-		http.Error(w, errorMsg, 499)
+	result, err := coalescedProbe(req, timeout)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	serveProbe(w, req, result, false)
+}
 
-	// Serve metrics normally if no errors
-	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
-	for _, mf := range metricFamilies {
-		if _, err := expfmt.MetricFamilyToText(w, mf); err != nil {
-			log.Printf("Error writing metrics: %v", err)
-			return
+// probeTimeout returns the probe deadline: the scrape timeout sent by Prometheus
+// minus TIMEOUT_OFFSET, capped by the module timeout
+func probeTimeout(r *http.Request, module config.ModuleConfig) (time.Duration, error) {
+	timeout := module.Timeout
+
+	if header := r.Header.Get("X-Prometheus-Scrape-Timeout-Seconds"); header != "" {
+		seconds, err := strconv.ParseFloat(header, 64)
+		if err != nil {
+			return 0, fmt.Errorf("Invalid X-Prometheus-Scrape-Timeout-Seconds header: %v", err)
+		}
+
+		scrapeTimeout := time.Duration(seconds*float64(time.Second)) - timeoutOffset
+		if scrapeTimeout <= 0 {
+			// Offset is larger than the scrape timeout, use half the scrape timeout instead
+			scrapeTimeout = time.Duration(seconds * float64(time.Second) / 2)
+		}
+		if timeout == 0 || scrapeTimeout < timeout {
+			timeout = scrapeTimeout
 		}
 	}
+
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	return timeout, nil
 }
 
 func healthCheckHandler(w http.ResponseWriter, r *http.Request) {
@@ -217,9 +387,10 @@ func indexHandler(w http.ResponseWriter, r *http.Request) {
         
         <h3>Parameters:</h3>
         <ul>
-            <li><strong>target</strong> (required): IP address and port of the Mikrotik device (e.g., 192.168.1.1:80)</li>
-            <li><strong>auth</strong> (optional): Authentication configuration name (default: "default")</li>
-            <li><strong>module</strong> (optional): Module configuration name (default: "default")</li>
+            <li><strong>target</strong> (required): IP address and port of the Mikrotik device (e.g., 192.168.1.1:80), or the name of a configured target</li>
+            <li><strong>auth</strong> (optional): Authentication configuration name (default: the target's auth, or "default")</li>
+            <li><strong>module</strong> (optional): Module configuration name (default: the target's module, or "default")</li>
+            <li><strong>debug</strong> (optional): "true" returns a JSON report of the requests, raw responses, parse errors and metrics of each collector (admin users only)</li>
         </ul>
         
         <h3>Available Collectors:</h3>
@@ -228,6 +399,9 @@ func indexHandler(w http.ResponseWriter, r *http.Request) {
 	for _, collectorName := range collectorRegistry.List() {
 		html += fmt.Sprintf("<li>%s</li>", collectorName)
 	}
+	for _, collectorName := range sortedCustomCollectors(cfg.Load().Config) {
+		html += fmt.Sprintf("<li>%s (custom)</li>", template.HTMLEscapeString(collectorName))
+	}
 
 	html += `        </ul>
         
@@ -248,6 +422,20 @@ func indexHandler(w http.ResponseWriter, r *http.Request) {
         <div class="endpoint">
             <code>/health-check</code> - Health check endpoint (returns JSON status)
         </div>
+        <div class="endpoint">
+            <code>/sd</code> - Prometheus HTTP service discovery of the configured and discovered targets (filters: module, auth, label=name=value)
+        </div>
+        <div class="endpoint">
+            <code>POST /-/reload</code> - Reload the configuration file (also triggered by SIGHUP)
+        </div>
+        
+        <h3>Configuration:</h3>
+        <pre class="endpoint">`
+
+	// Secrets are redacted by config.Secret
+	html += template.HTMLEscapeString(cfg.Load().String())
+
+	html += `</pre>
     </div>
 </body>
 </html>`
@@ -258,40 +446,115 @@ func indexHandler(w http.ResponseWriter, r *http.Request) {
 
 // ProbeCollector implements prometheus.Collector for multi-target probing
 type ProbeCollector struct {
-	target           string
-	auth             collector.AuthInfo
-	collectors       []collector.Collector
-	ctx              context.Context
-	errors           []error
-	collectorSuccess *prometheus.Desc
+	client            *routeros.Client
+	address           string
+	collectors        []collector.Collector
+	module            config.ModuleConfig
+	concurrency       int
+	ctx               context.Context
+	logger            *slog.Logger
+	errors            []error
+	collectorSuccess  *prometheus.Desc
+	collectorDuration *prometheus.Desc
+	probeSuccess      *prometheus.Desc
+	requestDuration   *prometheus.Desc
 }
 
 func (pc *ProbeCollector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- pc.collectorSuccess
+	ch <- pc.collectorDuration
+	ch <- pc.probeSuccess
+	ch <- pc.requestDuration
 	for _, c := range pc.collectors {
 		c.Describe(ch)
 	}
 }
 
+// Collect runs the collectors in parallel. At most pc.concurrency collectors
+// run against the target at a time, counting those of other probes of it.
 func (pc *ProbeCollector) Collect(ch chan<- prometheus.Metric) {
-	pc.errors = nil // Reset errors for each collection
-	for _, c := range pc.collectors {
-		success := 1.0
-		if err := c.Collect(pc.ctx, pc.target, pc.auth, ch); err != nil {
-			log.Printf("Error collecting metrics from %s collector: %v", c.Name(), err)
-			pc.errors = append(pc.errors, fmt.Errorf("%s: %w", c.Name(), err))
-			success = 0.0
-			// Continue with other collectors even if one fails
+	// Each collector only writes its own slot, so no locking is needed
+	collectorErrors := make([]error, len(pc.collectors))
+
+	var wg sync.WaitGroup
+	for i, c := range pc.collectors {
+		wg.Add(1)
+		go func(i int, c collector.Collector) {
+			defer wg.Done()
+
+			// Lines logged by the collector carry its name
+			logger := pc.logger.With("collector", c.Name())
+			duration, err := pc.runCollector(c, logger, ch)
+			success := 1.0
+			selfMetrics.collectorDuration.WithLabelValues(c.Name()).Observe(duration.Seconds())
+			if err != nil {
+				logger.Error("Error collecting metrics", "err", err)
+				selfMetrics.collectorErrors.WithLabelValues(c.Name()).Inc()
+				collectorErrors[i] = fmt.Errorf("%s: %w", c.Name(), err)
+				success = 0.0
+				// Continue with other collectors even if one fails
+			}
+
+			// Emit collector success metric
+			ch <- prometheus.MustNewConstMetric(
+				pc.collectorSuccess,
+				prometheus.GaugeValue,
+				success,
+				c.Name(),
+			)
+			ch <- prometheus.MustNewConstMetric(
+				pc.collectorDuration,
+				prometheus.GaugeValue,
+				duration.Seconds(),
+				c.Name(),
+			)
+		}(i, c)
+	}
+	wg.Wait()
+
+	// Keep errors in collector order so error messages are stable
+	pc.errors = nil
+	for _, err := range collectorErrors {
+		if err != nil {
+			pc.errors = append(pc.errors, err)
 		}
+	}
+
+	probeSuccess := 1.0
+	if len(pc.errors) > 0 {
+		probeSuccess = 0.0
+	}
+	ch <- prometheus.MustNewConstMetric(pc.probeSuccess, prometheus.GaugeValue, probeSuccess)
 
-		// Emit collector success metric
-		ch <- prometheus.MustNewConstMetric(
-			pc.collectorSuccess,
-			prometheus.GaugeValue,
-			success,
-			c.Name(),
-		)
+	// Emit per-endpoint request latency, summed if an endpoint was queried more than once
+	durations := make(map[string]float64)
+	for _, req := range pc.client.Requests() {
+		durations[req.Endpoint] += req.Duration.Seconds()
 	}
+	for endpoint, seconds := range durations {
+		ch <- prometheus.MustNewConstMetric(pc.requestDuration, prometheus.GaugeValue, seconds, endpoint)
+	}
+}
+
+// runCollector runs a collector once a slot on the target is free; the
+// returned duration does not include the wait
+func (pc *ProbeCollector) runCollector(c collector.Collector, logger *slog.Logger, ch chan<- prometheus.Metric) (time.Duration, error) {
+	concurrency := max(pc.concurrency, 1)
+	if err := targetSlots.acquire(pc.ctx, pc.address, concurrency); err != nil {
+		return 0, err
+	}
+	defer targetSlots.release(pc.address)
+
+	ctx := pc.ctx
+	if timeout := pc.module.GetCollectorTimeout(c.Name()); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := c.Collect(ctx, pc.client.WithLogger(logger), ch)
+	return time.Since(start), err
 }
 
 func getEnv(key, defaultValue string) string {
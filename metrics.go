@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/mikrotik-exporter/routeros"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// exporterMetrics describes the behavior of the exporter itself; they are
+// served on /metrics
+type exporterMetrics struct {
+	probeDuration     *prometheus.HistogramVec
+	probesInFlight    *prometheus.GaugeVec
+	collectorDuration *prometheus.HistogramVec
+	collectorErrors   *prometheus.CounterVec
+	requests          *prometheus.CounterVec
+	authFailures      *prometheus.CounterVec
+}
+
+// selfMetrics is set up in main() once the namespace is known
+var selfMetrics *exporterMetrics
+
+// newExporterMetrics creates the exporter metrics and registers them
+func newExporterMetrics(registry prometheus.Registerer) *exporterMetrics {
+	m := &exporterMetrics{
+		probeDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    metricsNamespace + "_probe_duration_seconds",
+			Help:    "Duration of probes against devices by module",
+			Buckets: []float64{.1, .25, .5, 1, 2.5, 5, 10, 20, 30, 60},
+		}, []string{"module"}),
+		probesInFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: metricsNamespace + "_probes_in_flight",
+			Help: "Number of probes currently running by module",
+		}, []string{"module"}),
+		collectorDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    metricsNamespace + "_collector_run_duration_seconds",
+			Help:    "Duration of collector runs by collector",
+			Buckets: []float64{.05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		}, []string{"collector"}),
+		collectorErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: metricsNamespace + "_collector_errors_total",
+			Help: "Total number of failed collector runs by collector",
+		}, []string{"collector"}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: metricsNamespace + "_device_requests_total",
+			Help: "Total number of requests to devices by endpoint and status code (0 for network errors and the native API)",
+		}, []string{"endpoint", "code"}),
+		authFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: metricsNamespace + "_auth_failures_total",
+			Help: "Total number of requests rejected by devices because of invalid credentials, by auth profile",
+		}, []string{"auth"}),
+	}
+
+	registry.MustRegister(
+		m.probeDuration,
+		m.probesInFlight,
+		m.collectorDuration,
+		m.collectorErrors,
+		m.requests,
+		m.authFailures,
+	)
+	return m
+}
+
+// observeRequest is the request hook of the client pool
+func (m *exporterMetrics) observeRequest(authName string, stat routeros.RequestStat) {
+	m.requests.WithLabelValues(stat.Endpoint, strconv.Itoa(stat.StatusCode)).Inc()
+	if errors.Is(stat.Err, routeros.ErrAuth) {
+		m.authFailures.WithLabelValues(authName).Inc()
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// poller is the background poller; nil if polling is disabled
+var poller *Poller
+
+// Poller probes the configured targets on a fixed interval and keeps the
+// latest result of each, so /probe doesn't have to contact the device
+type Poller struct {
+	interval time.Duration
+	maxAge   time.Duration
+
+	mu      sync.Mutex
+	results map[string]*probeResult
+}
+
+// NewPoller creates a poller. Results older than maxAge are reported as down.
+func NewPoller(interval, maxAge time.Duration) *Poller {
+	return &Poller{
+		interval: interval,
+		maxAge:   maxAge,
+		results:  make(map[string]*probeResult),
+	}
+}
+
+// Run polls all targets immediately and then on every interval; it never returns
+func (p *Poller) Run() {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.poll()
+		<-ticker.C
+	}
+}
+
+// poll probes every configured target (CIDR ranges excluded) with its
+// default auth and module, and drops results of removed targets
+func (p *Poller) poll() {
+	conf := cfg.Load()
+
+	var wg sync.WaitGroup
+	keys := make(map[string]bool)
+	for _, target := range conf.Targets {
+		if target.IsRange() {
+			continue
+		}
+
+		req, err := newProbeRequest(conf, target.Name, "", "")
+		if err != nil {
+			slog.Error("Error polling target", "target", target.Name, "err", err)
+			continue
+		}
+		keys[req.key()] = true
+
+		// Polls must finish before the next one starts
+		timeout := req.module.Timeout
+		if timeout <= 0 {
+			timeout = defaultProbeTimeout
+		}
+		if timeout > p.interval {
+			timeout = p.interval
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			result, err := coalescedProbe(req, timeout)
+			if err != nil {
+				req.logger.Error("Error polling target", "err", err)
+				return
+			}
+
+			p.mu.Lock()
+			p.results[req.key()] = result
+			p.mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	p.mu.Lock()
+	for key := range p.results {
+		if !keys[key] {
+			delete(p.results, key)
+		}
+	}
+	p.mu.Unlock()
+}
+
+// Get returns the latest result for a probe key, if the target is polled
+func (p *Poller) Get(key string) (*probeResult, bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result, ok := p.results[key]
+	return result, ok
+}
+
+// IsStale reports whether a result is too old to be served
+func (p *Poller) IsStale(result *probeResult) bool {
+	return time.Since(result.timestamp) > p.maxAge
+}
@@ -0,0 +1,359 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mikrotik-exporter/collector"
+	"github.com/mikrotik-exporter/collector/custom"
+	"github.com/mikrotik-exporter/config"
+	"github.com/mikrotik-exporter/routeros"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"golang.org/x/sync/singleflight"
+)
+
+// probeGroup coalesces concurrent probes of the same target, auth and module
+// into a single round trip to the device
+var probeGroup singleflight.Group
+
+// targetSlots limits the collectors running against each device across all
+// probes, which may use different modules
+var targetSlots = newTargetLimiter()
+
+// targetLimiter counts the collectors running against each address
+type targetLimiter struct {
+	mu      sync.Mutex
+	running map[string]int
+
+	// waiting is closed when a slot of the address is released
+	waiting map[string]chan struct{}
+}
+
+func newTargetLimiter() *targetLimiter {
+	return &targetLimiter{
+		running: make(map[string]int),
+		waiting: make(map[string]chan struct{}),
+	}
+}
+
+// acquire waits until fewer than limit collectors run against the address
+func (l *targetLimiter) acquire(ctx context.Context, address string, limit int) error {
+	for {
+		l.mu.Lock()
+		if l.running[address] < limit {
+			l.running[address]++
+			l.mu.Unlock()
+			return nil
+		}
+		released, exists := l.waiting[address]
+		if !exists {
+			released = make(chan struct{})
+			l.waiting[address] = released
+		}
+		l.mu.Unlock()
+
+		select {
+		case <-released:
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for a free slot on the target: %w", ctx.Err())
+		}
+	}
+}
+
+// release frees a slot acquired for the address
+func (l *targetLimiter) release(address string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.running[address]--
+	if l.running[address] <= 0 {
+		delete(l.running, address)
+	}
+	if released, exists := l.waiting[address]; exists {
+		close(released)
+		delete(l.waiting, address)
+	}
+}
+
+// probeRequest is a probe with the target defaults and configuration resolved
+type probeRequest struct {
+	target     string
+	address    string
+	authName   string
+	moduleName string
+	auth       config.AuthConfig
+	module     config.ModuleConfig
+	labels     map[string]string
+	collectors []collector.Collector
+
+	// logger adds the target, module and auth to every line logged during the probe
+	logger *slog.Logger
+}
+
+// probeResult holds the gathered metrics of a probe
+type probeResult struct {
+	families  []*dto.MetricFamily
+	gatherErr error
+	errors    []error
+	timestamp time.Time
+}
+
+// loadedConfig is the running configuration with the collectors of every
+// module, which are configured once per load instead of on every probe
+type loadedConfig struct {
+	*config.Config
+
+	// moduleCollectors are the enabled collectors of each module
+	moduleCollectors map[string][]collector.Collector
+}
+
+// newLoadedConfig configures the enabled collectors of every module; custom
+// collectors are defined by the configuration and run after the built-in ones
+func newLoadedConfig(conf *config.Config) (*loadedConfig, error) {
+	loaded := &loadedConfig{
+		Config:           conf,
+		moduleCollectors: make(map[string][]collector.Collector),
+	}
+	for name, module := range conf.Modules {
+		enabledCollectors, err := collectorRegistry.GetEnabled(module.Collectors)
+		if err != nil {
+			return nil, fmt.Errorf("module '%s': %w", name, err)
+		}
+		for _, customName := range sortedCustomCollectors(conf) {
+			if module.Collectors[customName].Enabled {
+				customCollector := custom.NewCollector(customName, conf.CustomCollectors[customName])
+				customCollector.SetNamespace(metricsNamespace)
+				enabledCollectors = append(enabledCollectors, customCollector)
+			}
+		}
+		loaded.moduleCollectors[name] = enabledCollectors
+	}
+	return loaded, nil
+}
+
+// newProbeRequest applies the defaults of a configured target and looks up
+// the auth and module configuration. Explicit auth and module names win.
+func newProbeRequest(conf *loadedConfig, target, authName, moduleName string) (probeRequest, error) {
+	targetConfig, address, _ := conf.ResolveTarget(target)
+	if authName == "" {
+		authName = targetConfig.Auth
+	}
+	if authName == "" {
+		authName = "default"
+	}
+	if moduleName == "" {
+		moduleName = targetConfig.Module
+	}
+	if moduleName == "" {
+		moduleName = "default"
+	}
+
+	// Get authentication configuration
+	authConfig, err := conf.GetAuth(authName)
+	if err != nil {
+		return probeRequest{}, fmt.Errorf("Auth configuration error: %v", err)
+	}
+
+	// Get module configuration
+	moduleConfig, err := conf.GetModule(moduleName)
+	if err != nil {
+		return probeRequest{}, fmt.Errorf("Module configuration error: %v", err)
+	}
+
+	enabledCollectors := conf.moduleCollectors[moduleName]
+	if len(enabledCollectors) == 0 {
+		return probeRequest{}, fmt.Errorf("No collectors enabled for this module")
+	}
+
+	return probeRequest{
+		target:     target,
+		address:    address,
+		authName:   authName,
+		moduleName: moduleName,
+		auth:       authConfig,
+		module:     moduleConfig,
+		labels:     targetConfig.Labels,
+		collectors: enabledCollectors,
+		logger:     slog.Default().With("target", target, "module", moduleName, "auth", authName),
+	}, nil
+}
+
+// sortedCustomCollectors returns the names of the custom collectors in sorted order
+func sortedCustomCollectors(conf *config.Config) []string {
+	names := make([]string, 0, len(conf.CustomCollectors))
+	for name := range conf.CustomCollectors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// key identifies probes that can share a result
+func (req probeRequest) key() string {
+	return req.target + "|" + req.authName + "|" + req.moduleName
+}
+
+// coalescedProbe runs a probe, or waits for the result of an identical probe
+// that is already running. The probe is not tied to a single HTTP request so
+// that a disconnecting client doesn't cancel it for the others.
+func coalescedProbe(req probeRequest, timeout time.Duration) (*probeResult, error) {
+	result, err, _ := probeGroup.Do(req.key(), func() (interface{}, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		return runProbe(ctx, req)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*probeResult), nil
+}
+
+// runProbe runs all enabled collectors of the module against the target
+func runProbe(ctx context.Context, req probeRequest) (*probeResult, error) {
+	// Get a client sharing the pooled connections to the target
+	client, err := clientPool.Client(req.address, routeros.Auth{
+		Name:      req.authName,
+		Username:  req.auth.Username,
+		Password:  string(req.auth.Password),
+		Transport: req.auth.Transport,
+		Scheme:    req.auth.Scheme,
+		TLS:       req.auth.TLS(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Auth configuration error: %v", err)
+	}
+	client = client.WithLogger(req.logger)
+
+	// Create a custom collector that will run all enabled collectors
+	probeCollector := newProbeCollector(ctx, client, req)
+
+	// Create a custom registry for this probe; extra labels of the target
+	// are added to every metric. Conflicts are rejected by Validate, but a
+	// probe must not bring the exporter down if one slips through.
+	registry := prometheus.NewRegistry()
+	if err := prometheus.WrapRegistererWith(req.labels, registry).Register(probeCollector); err != nil {
+		return nil, fmt.Errorf("Error registering metrics: %v", err)
+	}
+
+	inFlight := selfMetrics.probesInFlight.WithLabelValues(req.moduleName)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	result := &probeResult{timestamp: time.Now()}
+	result.families, result.gatherErr = registry.Gather()
+	selfMetrics.probeDuration.WithLabelValues(req.moduleName).Observe(time.Since(result.timestamp).Seconds())
+	if result.gatherErr != nil {
+		req.logger.Error("Error gathering metrics", "err", result.gatherErr)
+	}
+	result.errors = probeCollector.errors
+	return result, nil
+}
+
+// newProbeCollector creates the collector of a probe, which runs the enabled
+// collectors and reports their success and duration
+func newProbeCollector(ctx context.Context, client *routeros.Client, req probeRequest) *ProbeCollector {
+	return &ProbeCollector{
+		client:      client,
+		address:     req.address,
+		collectors:  req.collectors,
+		module:      req.module,
+		concurrency: req.module.GetConcurrency(),
+		ctx:         ctx,
+		logger:      req.logger,
+		collectorSuccess: prometheus.NewDesc(
+			metricsNamespace+"_collector_success",
+			"Whether a collector succeeded (1) or failed (0)",
+			[]string{"collector"},
+			nil,
+		),
+		collectorDuration: prometheus.NewDesc(
+			metricsNamespace+"_collector_duration_seconds",
+			"Duration of a collector scrape in seconds",
+			[]string{"collector"},
+			nil,
+		),
+		probeSuccess: prometheus.NewDesc(
+			metricsNamespace+"_probe_success",
+			"Whether all collectors of the probe succeeded (1) or not (0)",
+			nil,
+			nil,
+		),
+		requestDuration: prometheus.NewDesc(
+			metricsNamespace+"_request_duration_seconds",
+			"Duration of requests to the device by endpoint",
+			[]string{"endpoint"},
+			nil,
+		),
+	}
+}
+
+// newLastScrapeGauge creates the gauge served with every probe result
+func newLastScrapeGauge() prometheus.Gauge {
+	return prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: metricsNamespace + "_last_scrape_timestamp_seconds",
+		Help: "Time the metrics were collected from the device",
+	})
+}
+
+// serveProbe writes the metrics of a probe result. A stale result is not
+// served; the target is reported as down instead.
+func serveProbe(w http.ResponseWriter, req probeRequest, result *probeResult, stale bool) {
+	if !stale && req.module.FailOnError {
+		if result.gatherErr != nil {
+			http.Error(w, fmt.Sprintf("Error gathering metrics: %v", result.gatherErr), 499)
+			return
+		}
+
+		// In strict mode any collector error fails the whole probe
+		if len(result.errors) > 0 {
+			msgs := make([]string, len(result.errors))
+			for i, collectorErr := range result.errors {
+				msgs[i] = collectorErr.Error()
+			}
+			http.Error(w, "Collector errors: "+strings.Join(msgs, "; "), 499)
+			return
+		}
+	}
+
+	lastScrape := newLastScrapeGauge()
+	lastScrape.Set(float64(result.timestamp.UnixNano()) / 1e9)
+
+	meta := prometheus.NewRegistry()
+	registerer := prometheus.WrapRegistererWith(req.labels, meta)
+	registerer.MustRegister(lastScrape)
+
+	gatherers := prometheus.Gatherers{meta}
+	if stale {
+		probeSuccess := prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: metricsNamespace + "_probe_success",
+			Help: "Whether all collectors of the probe succeeded (1) or not (0)",
+		})
+		registerer.MustRegister(probeSuccess)
+	} else {
+		gatherers = append(gatherers, prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+			return result.families, nil
+		}))
+	}
+
+	metricFamilies, err := gatherers.Gather()
+	if err != nil {
+		req.logger.Error("Error merging metrics", "err", err)
+	}
+
+	// Serve whatever was collected; failed collectors are reported through _collector_success
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	for _, mf := range metricFamilies {
+		if _, err := expfmt.MetricFamilyToText(w, mf); err != nil {
+			req.logger.Error("Error writing metrics", "err", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/mikrotik-exporter/config"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ConfigReloader loads the configuration file and swaps it atomically on reload
+type ConfigReloader struct {
+	filename string
+	mu       sync.Mutex
+
+	lastReloadSuccessful prometheus.Gauge
+	lastReloadSuccessTS  prometheus.Gauge
+}
+
+// NewConfigReloader creates a reloader for the given file and registers its metrics
+func NewConfigReloader(filename string, registry prometheus.Registerer) *ConfigReloader {
+	cr := &ConfigReloader{
+		filename: filename,
+		lastReloadSuccessful: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: metricsNamespace + "_config_last_reload_successful",
+			Help: "Whether the last configuration reload attempt was successful",
+		}),
+		lastReloadSuccessTS: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: metricsNamespace + "_config_last_reload_success_timestamp_seconds",
+			Help: "Timestamp of the last successful configuration reload",
+		}),
+	}
+	registry.MustRegister(cr.lastReloadSuccessful, cr.lastReloadSuccessTS)
+	return cr
+}
+
+// Reload re-reads the configuration file. The running configuration is only
+// replaced if the new one loads successfully.
+func (cr *ConfigReloader) Reload() error {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+
+	newConfig, err := config.LoadConfig(cr.filename)
+	if err != nil {
+		cr.lastReloadSuccessful.Set(0)
+		return err
+	}
+
+	warnings, err := newConfig.Validate(collectorCatalog{collectorRegistry})
+	for _, warning := range warnings {
+		slog.Warn("Configuration warning", "file", cr.filename, "warning", warning)
+	}
+	if err != nil {
+		cr.lastReloadSuccessful.Set(0)
+		return err
+	}
+
+	loaded, err := newLoadedConfig(newConfig)
+	if err != nil {
+		cr.lastReloadSuccessful.Set(0)
+		return err
+	}
+	cfg.Store(loaded)
+
+	// Drop pooled connections so changed credentials and TLS settings take effect
+	clientPool.Reset()
+
+	cr.lastReloadSuccessful.Set(1)
+	cr.lastReloadSuccessTS.SetToCurrentTime()
+	return nil
+}
+
+// WatchSignals reloads the configuration whenever the process receives SIGHUP
+func (cr *ConfigReloader) WatchSignals() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+
+	go func() {
+		for range hup {
+			if err := cr.Reload(); err != nil {
+				slog.Error("Error reloading configuration", "file", cr.filename, "err", err)
+				continue
+			}
+			slog.Info("Configuration reloaded", "file", cr.filename)
+		}
+	}()
+}
+
+// ServeHTTP handles POST /-/reload
+func (cr *ConfigReloader) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "Only POST requests are allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := cr.Reload(); err != nil {
+		slog.Error("Error reloading configuration", "file", cr.filename, "err", err)
+		http.Error(w, fmt.Sprintf("Failed to reload configuration: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	slog.Info("Configuration reloaded", "file", cr.filename)
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,393 @@
+package routeros
+
+import (
+	"bufio"
+	"context"
+	"crypto/md5"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default ports of the native API services
+const (
+	apiPort    = "8728"
+	apiTLSPort = "8729"
+)
+
+// maxIdleAPIConns is the number of logged-in connections kept per target
+const maxIdleAPIConns = 2
+
+// apiTransport talks to the native RouterOS API and keeps a small pool of
+// logged-in connections
+type apiTransport struct {
+	address string
+	auth    Auth
+
+	mu     sync.Mutex
+	idle   []*apiConn
+	closed bool
+}
+
+func newAPITransport(target string, auth Auth) *apiTransport {
+	address := target
+	if _, _, err := net.SplitHostPort(target); err != nil {
+		port := apiPort
+		if auth.Scheme == "https" {
+			port = apiTLSPort
+		}
+		address = net.JoinHostPort(strings.Trim(target, "[]"), port)
+	}
+
+	return &apiTransport{
+		address: address,
+		auth:    auth,
+	}
+}
+
+// words returns the API sentence of a request
+func (t *apiTransport) words(r Request) []string {
+	words := []string{r.Path + "/print"}
+	if len(r.Proplist) > 0 {
+		words = append(words, "=.proplist="+strings.Join(r.Proplist, ","))
+	}
+	for _, key := range r.filterKeys() {
+		words = append(words, "?"+key+"="+r.Filter[key])
+	}
+	return words
+}
+
+// url describes a request for logging
+func (t *apiTransport) url(r Request) string {
+	scheme := "api"
+	if t.auth.Scheme == "https" {
+		scheme = "api-ssl"
+	}
+	return fmt.Sprintf("%s://%s %s", scheme, t.address, strings.Join(t.words(r), " "))
+}
+
+func (t *apiTransport) do(ctx context.Context, r Request) ([]byte, int, error) {
+	conn, err := t.get(ctx)
+	if err != nil {
+		return nil, 0, t.wrapError(ctx, r.Path, err)
+	}
+
+	records, err := conn.run(ctx, t.words(r)...)
+	if err != nil {
+		var trap *apiTrap
+		if errors.As(err, &trap) {
+			// The connection is still usable after a !trap
+			t.put(conn)
+		} else {
+			conn.Close()
+		}
+		return nil, 0, t.wrapError(ctx, r.Path, err)
+	}
+	t.put(conn)
+
+	body, err := json.Marshal(records)
+	if err != nil {
+		return nil, 0, &Error{Endpoint: r.Path, Err: err}
+	}
+	return body, 0, nil
+}
+
+// get returns an idle connection or dials and logs in a new one
+func (t *apiTransport) get(ctx context.Context) (*apiConn, error) {
+	t.mu.Lock()
+	if n := len(t.idle); n > 0 {
+		conn := t.idle[n-1]
+		t.idle = t.idle[:n-1]
+		t.mu.Unlock()
+		return conn, nil
+	}
+	t.mu.Unlock()
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}
+	var (
+		netConn net.Conn
+		err     error
+	)
+	if t.auth.Scheme == "https" {
+		tlsConfig := t.auth.TLS
+		if tlsConfig == nil {
+			tlsConfig = &tls.Config{}
+		}
+		netConn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", t.address)
+	} else {
+		netConn, err = dialer.DialContext(ctx, "tcp", t.address)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	conn := newAPIConn(netConn)
+	if err := conn.login(ctx, t.auth.Username, t.auth.Password); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+// put returns a connection to the idle pool
+func (t *apiTransport) put(conn *apiConn) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.closed || len(t.idle) >= maxIdleAPIConns {
+		conn.Close()
+		return
+	}
+	t.idle = append(t.idle, conn)
+}
+
+// close closes all idle connections; connections in use are closed when returned
+func (t *apiTransport) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, conn := range t.idle {
+		conn.Close()
+	}
+	t.idle = nil
+	t.closed = true
+}
+
+// wrapError maps API and network errors to typed errors
+func (t *apiTransport) wrapError(ctx context.Context, path string, err error) error {
+	if errors.Is(err, ErrAuth) {
+		return &Error{Endpoint: path, Err: ErrAuth}
+	}
+
+	var trap *apiTrap
+	if errors.As(err, &trap) {
+		switch {
+		case strings.Contains(trap.message, "no such command"):
+			return &Error{Endpoint: path, Err: ErrNotFound, Detail: trap.message}
+		case strings.Contains(trap.message, "busy") || strings.Contains(trap.message, "timeout"):
+			return &Error{Endpoint: path, Err: ErrBusy, Detail: trap.message}
+		}
+		return &Error{Endpoint: path, Err: trap}
+	}
+
+	if isTimeout(ctx, err) {
+		return &Error{Endpoint: path, Err: ErrTimeout, Detail: err.Error()}
+	}
+	return &Error{Endpoint: path, Err: err}
+}
+
+// apiTrap is an error reply (!trap) sent by the device
+type apiTrap struct {
+	message string
+}
+
+func (e *apiTrap) Error() string {
+	return e.message
+}
+
+// apiConn is a single logged-in API connection
+type apiConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+func newAPIConn(conn net.Conn) *apiConn {
+	return &apiConn{
+		conn:   conn,
+		reader: bufio.NewReader(conn),
+	}
+}
+
+// Close closes the underlying network connection
+func (c *apiConn) Close() error {
+	return c.conn.Close()
+}
+
+// login authenticates using the post-6.43 method and falls back to the
+// legacy challenge-response method if the device asks for it
+func (c *apiConn) login(ctx context.Context, username, password string) error {
+	replies, err := c.run(ctx, "/login", "=name="+username, "=password="+password)
+	if err != nil {
+		var trap *apiTrap
+		if errors.As(err, &trap) {
+			return fmt.Errorf("%w: %s", ErrAuth, trap.message)
+		}
+		return err
+	}
+
+	// Pre-6.43 devices answer with a challenge instead of logging in
+	if len(replies) == 0 || replies[0]["ret"] == "" {
+		return nil
+	}
+
+	challenge, err := hex.DecodeString(replies[0]["ret"])
+	if err != nil {
+		return fmt.Errorf("invalid login challenge: %w", err)
+	}
+	hash := md5.New()
+	hash.Write([]byte{0})
+	hash.Write([]byte(password))
+	hash.Write(challenge)
+	response := "00" + hex.EncodeToString(hash.Sum(nil))
+
+	if _, err := c.run(ctx, "/login", "=name="+username, "=response="+response); err != nil {
+		var trap *apiTrap
+		if errors.As(err, &trap) {
+			return fmt.Errorf("%w: %s", ErrAuth, trap.message)
+		}
+		return err
+	}
+	return nil
+}
+
+// run sends a command and collects the attributes of every !re reply. The
+// attributes of the final !done reply are returned as the only record when
+// there are no !re replies (used by the legacy login).
+func (c *apiConn) run(ctx context.Context, words ...string) ([]map[string]string, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() {
+		// Unblock pending reads and writes on cancellation
+		c.conn.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	if err := c.writeSentence(words); err != nil {
+		return nil, err
+	}
+
+	records := []map[string]string{}
+	var trap error
+	for {
+		sentence, err := c.readSentence()
+		if err != nil {
+			return nil, err
+		}
+		if len(sentence) == 0 {
+			continue
+		}
+
+		attributes := parseAttributes(sentence[1:])
+		switch sentence[0] {
+		case "!re":
+			records = append(records, attributes)
+		case "!trap":
+			trap = &apiTrap{message: attributes["message"]}
+		case "!fatal":
+			message := strings.Join(sentence[1:], " ")
+			return nil, fmt.Errorf("fatal: %s", message)
+		case "!done":
+			if trap != nil {
+				return nil, trap
+			}
+			if len(records) == 0 && len(attributes) > 0 {
+				records = append(records, attributes)
+			}
+			return records, nil
+		}
+	}
+}
+
+// parseAttributes converts "=key=value" words to a map
+func parseAttributes(words []string) map[string]string {
+	attributes := make(map[string]string)
+	for _, word := range words {
+		if !strings.HasPrefix(word, "=") {
+			continue
+		}
+		key, value, _ := strings.Cut(word[1:], "=")
+		attributes[key] = value
+	}
+	return attributes
+}
+
+// writeSentence writes the words followed by the zero-length terminator
+func (c *apiConn) writeSentence(words []string) error {
+	var buf []byte
+	for _, word := range words {
+		buf = appendLength(buf, len(word))
+		buf = append(buf, word...)
+	}
+	buf = append(buf, 0)
+
+	_, err := c.conn.Write(buf)
+	return err
+}
+
+// readSentence reads words until the zero-length terminator
+func (c *apiConn) readSentence() ([]string, error) {
+	var words []string
+	for {
+		length, err := readLength(c.reader)
+		if err != nil {
+			return nil, err
+		}
+		if length == 0 {
+			return words, nil
+		}
+
+		word := make([]byte, length)
+		if _, err := io.ReadFull(c.reader, word); err != nil {
+			return nil, err
+		}
+		words = append(words, string(word))
+	}
+}
+
+// appendLength appends the API encoding of a word length
+func appendLength(buf []byte, length int) []byte {
+	switch {
+	case length < 0x80:
+		return append(buf, byte(length))
+	case length < 0x4000:
+		return append(buf, byte(length>>8)|0x80, byte(length))
+	case length < 0x200000:
+		return append(buf, byte(length>>16)|0xC0, byte(length>>8), byte(length))
+	case length < 0x10000000:
+		return append(buf, byte(length>>24)|0xE0, byte(length>>16), byte(length>>8), byte(length))
+	default:
+		return append(buf, 0xF0, byte(length>>24), byte(length>>16), byte(length>>8), byte(length))
+	}
+}
+
+// readLength reads an API encoded word length
+func readLength(r *bufio.Reader) (int, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+
+	var extra int
+	length := int(first)
+	switch {
+	case first&0x80 == 0x00:
+		return length, nil
+	case first&0xC0 == 0x80:
+		extra, length = 1, length&^0xC0
+	case first&0xE0 == 0xC0:
+		extra, length = 2, length&^0xE0
+	case first&0xF0 == 0xE0:
+		extra, length = 3, length&^0xF0
+	case first == 0xF0:
+		extra, length = 4, 0
+	default:
+		return 0, fmt.Errorf("invalid word length prefix 0x%02x", first)
+	}
+
+	for i := 0; i < extra; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		length = length<<8 | int(b)
+	}
+	return length, nil
+}
@@ -0,0 +1,376 @@
+package routeros
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLengthEncoding(t *testing.T) {
+	tests := []struct {
+		length  int
+		encoded []byte
+	}{
+		{0x00, []byte{0x00}},
+		{0x7F, []byte{0x7F}},
+		{0x80, []byte{0x80, 0x80}},
+		{0x3FFF, []byte{0xBF, 0xFF}},
+		{0x4000, []byte{0xC0, 0x40, 0x00}},
+		{0x1FFFFF, []byte{0xDF, 0xFF, 0xFF}},
+		{0x200000, []byte{0xE0, 0x20, 0x00, 0x00}},
+		{0xFFFFFFF, []byte{0xEF, 0xFF, 0xFF, 0xFF}},
+		{0x10000000, []byte{0xF0, 0x10, 0x00, 0x00, 0x00}},
+		{0x7FFFFFFF, []byte{0xF0, 0x7F, 0xFF, 0xFF, 0xFF}},
+	}
+
+	for _, tt := range tests {
+		encoded := appendLength(nil, tt.length)
+		if !bytes.Equal(encoded, tt.encoded) {
+			t.Errorf("appendLength(0x%X) = % X, want % X", tt.length, encoded, tt.encoded)
+		}
+
+		length, err := readLength(bufio.NewReader(bytes.NewReader(tt.encoded)))
+		if err != nil {
+			t.Errorf("readLength(% X) error = %v", tt.encoded, err)
+			continue
+		}
+		if length != tt.length {
+			t.Errorf("readLength(% X) = 0x%X, want 0x%X", tt.encoded, length, tt.length)
+		}
+	}
+}
+
+func TestReadLengthErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		encoded []byte
+	}{
+		{"invalid prefix", []byte{0xF8}},
+		{"truncated two bytes", []byte{0x80}},
+		{"truncated five bytes", []byte{0xF0, 0x01, 0x02}},
+		{"empty", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := readLength(bufio.NewReader(bytes.NewReader(tt.encoded))); err == nil {
+				t.Errorf("readLength(% X) succeeded, want error", tt.encoded)
+			}
+		})
+	}
+}
+
+func TestReadSentence(t *testing.T) {
+	long := strings.Repeat("x", 0x90)
+
+	var buf []byte
+	for _, word := range []string{"!re", "=name=ether1", "=comment=" + long} {
+		buf = appendLength(buf, len(word))
+		buf = append(buf, word...)
+	}
+	buf = append(buf, 0)
+
+	client, server := net.Pipe()
+	defer client.Close()
+	go func() {
+		server.Write(buf)
+		server.Close()
+	}()
+
+	conn := newAPIConn(client)
+	words, err := conn.readSentence()
+	if err != nil {
+		t.Fatalf("readSentence() error = %v", err)
+	}
+	want := []string{"!re", "=name=ether1", "=comment=" + long}
+	if !reflect.DeepEqual(words, want) {
+		t.Errorf("readSentence() = %q, want %q", words, want)
+	}
+
+	if _, err := conn.readSentence(); err == nil {
+		t.Error("readSentence() on closed connection succeeded, want error")
+	}
+}
+
+// fakeAPIServer is a minimal RouterOS API service
+type fakeAPIServer struct {
+	listener net.Listener
+	username string
+	password string
+
+	// legacy makes the server use the pre-6.43 challenge-response login
+	legacy bool
+
+	// replies are the sentences sent for a command, without the final !done;
+	// unknown commands are answered with a !trap
+	replies map[string][][]string
+
+	mu       sync.Mutex
+	conns    int
+	commands [][]string
+}
+
+// challenge is the legacy login challenge sent by the fake server
+const challenge = "0123456789abcdef0123456789abcdef"
+
+func newFakeAPIServer(t *testing.T) *fakeAPIServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &fakeAPIServer{
+		listener: listener,
+		username: "admin",
+		password: "secret",
+		replies:  make(map[string][][]string),
+	}
+	go s.serve()
+	t.Cleanup(func() { listener.Close() })
+	return s
+}
+
+func (s *fakeAPIServer) address() string {
+	return s.listener.Addr().String()
+}
+
+func (s *fakeAPIServer) serve() {
+	for {
+		netConn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns++
+		s.mu.Unlock()
+		go s.handle(newAPIConn(netConn))
+	}
+}
+
+// connections returns the number of accepted connections
+func (s *fakeAPIServer) connections() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns
+}
+
+func (s *fakeAPIServer) handle(conn *apiConn) {
+	defer conn.Close()
+
+	loggedIn := false
+	for {
+		words, err := conn.readSentence()
+		if err != nil || len(words) == 0 {
+			return
+		}
+		attributes := parseAttributes(words[1:])
+
+		if words[0] == "/login" {
+			switch {
+			case s.legacy && attributes["response"] == "":
+				// Pre-6.43 devices ignore the password and send a challenge
+				conn.writeSentence([]string{"!done", "=ret=" + challenge})
+			case s.legacy:
+				if attributes["name"] == s.username && attributes["response"] == legacyResponse(s.password) {
+					loggedIn = true
+					conn.writeSentence([]string{"!done"})
+				} else {
+					s.trap(conn, "cannot log in")
+				}
+			default:
+				if attributes["name"] == s.username && attributes["password"] == s.password {
+					loggedIn = true
+					conn.writeSentence([]string{"!done"})
+				} else {
+					s.trap(conn, "invalid user name or password (6)")
+				}
+			}
+			continue
+		}
+
+		if !loggedIn {
+			conn.writeSentence([]string{"!fatal", "not logged in"})
+			return
+		}
+
+		s.mu.Lock()
+		s.commands = append(s.commands, words)
+		s.mu.Unlock()
+
+		replies, exists := s.replies[words[0]]
+		if !exists {
+			s.trap(conn, "no such command prefix")
+			continue
+		}
+		for _, reply := range replies {
+			conn.writeSentence(reply)
+			if reply[0] == "!fatal" {
+				return
+			}
+		}
+		conn.writeSentence([]string{"!done"})
+	}
+}
+
+// trap sends an error reply
+func (s *fakeAPIServer) trap(conn *apiConn, message string) {
+	conn.writeSentence([]string{"!trap", "=message=" + message})
+	conn.writeSentence([]string{"!done"})
+}
+
+// legacyResponse computes the expected pre-6.43 login response
+func legacyResponse(password string) string {
+	decoded, _ := hex.DecodeString(challenge)
+	hash := md5.New()
+	hash.Write([]byte{0})
+	hash.Write([]byte(password))
+	hash.Write(decoded)
+	return "00" + hex.EncodeToString(hash.Sum(nil))
+}
+
+// apiClient returns a client for the fake server
+func apiClient(t *testing.T, s *fakeAPIServer, password string) *Client {
+	t.Helper()
+	client, err := NewPool().Client(s.address(), Auth{
+		Name:      "test",
+		Username:  "admin",
+		Password:  password,
+		Transport: TransportAPI,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func testContext(t *testing.T) context.Context {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestAPILogin(t *testing.T) {
+	tests := []struct {
+		name     string
+		legacy   bool
+		password string
+		wantErr  error
+	}{
+		{name: "post-6.43", password: "secret"},
+		{name: "post-6.43 wrong password", password: "wrong", wantErr: ErrAuth},
+		{name: "legacy challenge", legacy: true, password: "secret"},
+		{name: "legacy challenge wrong password", legacy: true, password: "wrong", wantErr: ErrAuth},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newFakeAPIServer(t)
+			server.legacy = tt.legacy
+			server.replies["/system/identity/print"] = [][]string{{"!re", "=name=core-rtr-1"}}
+
+			var identity struct {
+				Name string `json:"name"`
+			}
+			err := apiClient(t, server, tt.password).Get(testContext(t), "/system/identity", &identity)
+
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Get() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if identity.Name != "core-rtr-1" {
+				t.Errorf("Get() name = %q, want %q", identity.Name, "core-rtr-1")
+			}
+		})
+	}
+}
+
+func TestAPIGet(t *testing.T) {
+	server := newFakeAPIServer(t)
+	server.replies["/interface/print"] = [][]string{
+		{"!re", "=name=ether1", "=running=true"},
+		{"!re", "=name=ether2", "=running=false"},
+	}
+
+	var interfaces []struct {
+		Name    string `json:"name"`
+		Running string `json:"running"`
+	}
+	err := apiClient(t, server, "secret").Get(testContext(t), "/interface", &interfaces,
+		Proplist("name", "running"), Where("type", "ether"))
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if len(interfaces) != 2 || interfaces[0].Name != "ether1" || interfaces[1].Running != "false" {
+		t.Errorf("Get() = %+v", interfaces)
+	}
+
+	want := []string{"/interface/print", "=.proplist=name,running", "?type=ether"}
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.commands) != 1 || !reflect.DeepEqual(server.commands[0], want) {
+		t.Errorf("commands = %q, want [%q]", server.commands, want)
+	}
+}
+
+func TestAPITrapKeepsConnection(t *testing.T) {
+	server := newFakeAPIServer(t)
+	server.replies["/system/identity/print"] = [][]string{{"!re", "=name=core-rtr-1"}}
+	client := apiClient(t, server, "secret")
+	ctx := testContext(t)
+
+	var routes []map[string]string
+	err := client.Get(ctx, "/routing/bgp/session", &routes)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() error = %v, want %v", err, ErrNotFound)
+	}
+
+	var identity struct {
+		Name string `json:"name"`
+	}
+	if err := client.Get(ctx, "/system/identity", &identity); err != nil {
+		t.Fatalf("Get() after !trap error = %v", err)
+	}
+	if identity.Name != "core-rtr-1" {
+		t.Errorf("Get() name = %q, want %q", identity.Name, "core-rtr-1")
+	}
+	if n := server.connections(); n != 1 {
+		t.Errorf("connections = %d, want 1 (reused after !trap)", n)
+	}
+}
+
+func TestAPIFatalClosesConnection(t *testing.T) {
+	server := newFakeAPIServer(t)
+	server.replies["/system/resource/print"] = [][]string{{"!fatal", "session terminated on request"}}
+	server.replies["/system/identity/print"] = [][]string{{"!re", "=name=core-rtr-1"}}
+	client := apiClient(t, server, "secret")
+	ctx := testContext(t)
+
+	var resource map[string]string
+	err := client.Get(ctx, "/system/resource", &resource)
+	if err == nil || !strings.Contains(err.Error(), "session terminated on request") {
+		t.Fatalf("Get() error = %v, want !fatal message", err)
+	}
+
+	var identity map[string]string
+	if err := client.Get(ctx, "/system/identity", &identity); err != nil {
+		t.Fatalf("Get() after !fatal error = %v", err)
+	}
+	if n := server.connections(); n != 2 {
+		t.Errorf("connections = %d, want 2 (new connection after !fatal)", n)
+	}
+}
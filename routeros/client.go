@@ -0,0 +1,332 @@
+package routeros
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout is the maximum duration of a single request when the
+// context has no deadline
+const DefaultTimeout = 10 * time.Second
+
+// Supported transports
+const (
+	// TransportREST uses the REST API (RouterOS 7.1+, www or www-ssl service)
+	TransportREST = "rest"
+
+	// TransportAPI uses the native binary API (api or api-ssl service)
+	TransportAPI = "api"
+)
+
+// Auth contains authentication and connection settings for a Mikrotik device
+type Auth struct {
+	// Name identifies the auth profile; connections are pooled per target and profile
+	Name     string
+	Username string
+	Password string
+
+	// Transport is either "rest" (default) or "api"
+	Transport string
+
+	// Scheme is either "http" (default) or "https". With the API transport
+	// "https" selects the api-ssl service.
+	Scheme string
+
+	// TLS is used for HTTPS and api-ssl connections
+	TLS *tls.Config
+}
+
+// Request describes a query for a RouterOS menu
+type Request struct {
+	// Path is the menu path, e.g. "/interface"
+	Path string
+
+	// Proplist limits the returned properties
+	Proplist []string
+
+	// Filter only returns entries whose properties match the given values
+	Filter map[string]string
+}
+
+// RequestOption customizes a Request
+type RequestOption func(*Request)
+
+// Proplist limits the properties returned by the device
+func Proplist(properties ...string) RequestOption {
+	return func(r *Request) {
+		r.Proplist = append(r.Proplist, properties...)
+	}
+}
+
+// Where only returns entries whose property equals value
+func Where(property, value string) RequestOption {
+	return func(r *Request) {
+		if r.Filter == nil {
+			r.Filter = make(map[string]string)
+		}
+		r.Filter[property] = value
+	}
+}
+
+// filterKeys returns the filter properties in a stable order
+func (r Request) filterKeys() []string {
+	keys := make([]string, 0, len(r.Filter))
+	for key := range r.Filter {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// transport executes requests and returns the response as JSON
+type transport interface {
+	do(ctx context.Context, req Request) (body []byte, statusCode int, err error)
+
+	// url describes the request for debug logging
+	url(req Request) string
+}
+
+// RequestStat describes a single request made by a Client
+type RequestStat struct {
+	Endpoint   string
+	StatusCode int
+	Duration   time.Duration
+	Err        error
+}
+
+// RequestHook is called after every request made by a client of the pool,
+// with the name of the auth profile the client uses
+type RequestHook func(authName string, stat RequestStat)
+
+// Pool keeps one keep-alive connection pool per target so that all collectors
+// of a probe (and subsequent probes) reuse the same connections
+type Pool struct {
+	mu       sync.Mutex
+	rest     map[string]*restTransport
+	api      map[string]*apiTransport
+	versions *versionCache
+	hook     RequestHook
+}
+
+// NewPool creates a new client pool
+func NewPool() *Pool {
+	return &Pool{
+		rest:     make(map[string]*restTransport),
+		api:      make(map[string]*apiTransport),
+		versions: newVersionCache(),
+	}
+}
+
+// Client returns a client for the given target using the pooled connections
+func (p *Pool) Client(target string, auth Auth) (*Client, error) {
+	if auth.Scheme == "" {
+		auth.Scheme = "http"
+	}
+	if auth.Transport == "" {
+		auth.Transport = TransportREST
+	}
+
+	t, err := p.transport(target, auth)
+	if err != nil {
+		return nil, err
+	}
+
+	p.mu.Lock()
+	hook := p.hook
+	p.mu.Unlock()
+
+	return &Client{
+		target:    target,
+		authName:  auth.Name,
+		transport: t,
+		versions:  p.versions,
+		hook:      hook,
+		logger:    slog.Default(),
+		stats:     &requestLog{},
+	}, nil
+}
+
+// SetRequestHook sets a hook called after every request of the clients
+// created from now on, e.g. to count requests
+func (p *Pool) SetRequestHook(hook RequestHook) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.hook = hook
+}
+
+// Reset closes all pooled connections so that new clients are created with
+// the current credentials and TLS settings. Requests in flight are not affected.
+func (p *Pool) Reset() {
+	p.mu.Lock()
+	rest, api := p.rest, p.api
+	p.rest = make(map[string]*restTransport)
+	p.api = make(map[string]*apiTransport)
+	p.mu.Unlock()
+
+	for _, t := range rest {
+		t.close()
+	}
+	for _, t := range api {
+		t.close()
+	}
+}
+
+// transport returns the shared transport for the target, creating it if needed
+func (p *Pool) transport(target string, auth Auth) (transport, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	key := auth.Transport + "+" + auth.Scheme + "://" + target + "/" + auth.Name
+
+	switch auth.Transport {
+	case TransportREST:
+		if t, exists := p.rest[key]; exists {
+			return t, nil
+		}
+		t := newRESTTransport(target, auth)
+		p.rest[key] = t
+		return t, nil
+	case TransportAPI:
+		if t, exists := p.api[key]; exists {
+			return t, nil
+		}
+		t := newAPITransport(target, auth)
+		p.api[key] = t
+		return t, nil
+	default:
+		return nil, fmt.Errorf("unsupported transport '%s'", auth.Transport)
+	}
+}
+
+// Client performs requests against a single Mikrotik device
+type Client struct {
+	target    string
+	authName  string
+	transport transport
+	versions  *versionCache
+	hook      RequestHook
+	logger    *slog.Logger
+	trace     *Trace
+
+	// stats is shared with the copies made by WithLogger
+	stats *requestLog
+}
+
+// requestLog records the requests of a client
+type requestLog struct {
+	mu       sync.Mutex
+	requests []RequestStat
+}
+
+// Target returns the address of the device
+func (c *Client) Target() string {
+	return c.target
+}
+
+// Requests returns the statistics of all requests made by the client (and
+// its copies) so far
+func (c *Client) Requests() []RequestStat {
+	c.stats.mu.Lock()
+	defer c.stats.mu.Unlock()
+
+	requests := make([]RequestStat, len(c.stats.requests))
+	copy(requests, c.stats.requests)
+	return requests
+}
+
+// Logger returns the logger of the client
+func (c *Client) Logger() *slog.Logger {
+	return c.logger
+}
+
+// WithLogger returns a copy of the client that logs to logger. The copy shares
+// the connections and request statistics of the client.
+func (c *Client) WithLogger(logger *slog.Logger) *Client {
+	clone := *c
+	clone.logger = logger
+	return &clone
+}
+
+// Get fetches the given menu path (e.g. "/interface") and decodes the
+// response into out, which must be a pointer to a struct or a slice of structs
+func (c *Client) Get(ctx context.Context, path string, out interface{}, opts ...RequestOption) error {
+	req := Request{Path: path}
+	for _, opt := range opts {
+		opt(&req)
+	}
+
+	// Requests are bounded by the probe deadline; fall back to a default
+	// so a request can never hang forever
+	if _, hasDeadline := ctx.Deadline(); !hasDeadline {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultTimeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	body, statusCode, err := c.transport.do(ctx, req)
+	if err == nil {
+		if decodeErr := decode(body, out); decodeErr != nil {
+			err = &Error{Endpoint: path, StatusCode: statusCode, Err: fmt.Errorf("failed to decode response: %w", decodeErr)}
+		}
+	}
+
+	stat := RequestStat{
+		Endpoint:   path,
+		StatusCode: statusCode,
+		Duration:   time.Since(start),
+		Err:        err,
+	}
+	c.stats.mu.Lock()
+	c.stats.requests = append(c.stats.requests, stat)
+	c.stats.mu.Unlock()
+
+	if c.trace != nil {
+		c.trace.addRequest(TracedRequest{
+			RequestStat: stat,
+			URL:         c.transport.url(req),
+			Body:        body,
+		})
+	}
+
+	if c.logger.Enabled(ctx, slog.LevelDebug) {
+		c.logger.DebugContext(ctx, "Request to device",
+			"url", c.transport.url(req),
+			"status", statusCode,
+			"duration", stat.Duration,
+			"err", err,
+		)
+	}
+
+	if c.hook != nil {
+		c.hook(c.authName, stat)
+	}
+	return err
+}
+
+// decode unmarshals a JSON response into out. Transports may return a list
+// for menus with a single entry (e.g. /system/resource over the API), so a
+// list is unwrapped when out is not a slice.
+func decode(body []byte, out interface{}) error {
+	body = bytes.TrimSpace(body)
+	if len(body) > 0 && body[0] == '[' && reflect.Indirect(reflect.ValueOf(out)).Kind() != reflect.Slice {
+		var items []json.RawMessage
+		if err := json.Unmarshal(body, &items); err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			return nil
+		}
+		body = items[0]
+	}
+	return json.Unmarshal(body, out)
+}
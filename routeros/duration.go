@@ -0,0 +1,73 @@
+package routeros
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// durationRegexp matches RouterOS durations such as "2w4d1h12m27s" or "36m5s950ms"
+var durationRegexp = regexp.MustCompile(`^(?:(\d+)w)?(?:(\d+)d)?(?:(\d+)h)?(?:(\d+)m)?(?:(\d+)s)?(?:(\d+)ms)?(?:(\d+)us)?$`)
+
+// clockRegexp matches durations in clock format such as "1d02:03:04" or "00:00:05.5"
+var clockRegexp = regexp.MustCompile(`^(?:(\d+)w)?(?:(\d+)d)?(\d+):(\d{2}):(\d{2})(\.\d+)?$`)
+
+// durationUnits are the units of the groups of durationRegexp
+var durationUnits = []time.Duration{
+	7 * 24 * time.Hour,
+	24 * time.Hour,
+	time.Hour,
+	time.Minute,
+	time.Second,
+	time.Millisecond,
+	time.Microsecond,
+}
+
+// ParseDuration parses a duration as reported by RouterOS, e.g. uptimes
+// ("2w4d1h12m27s"), timers ("36m5s950ms") or the clock format ("1d02:03:04")
+func ParseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty duration")
+	}
+
+	if matches := durationRegexp.FindStringSubmatch(s); matches != nil {
+		var total time.Duration
+		for i, unit := range durationUnits {
+			if matches[i+1] == "" {
+				continue
+			}
+			value, err := strconv.ParseInt(matches[i+1], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration: %s", s)
+			}
+			total += time.Duration(value) * unit
+		}
+		return total, nil
+	}
+
+	if matches := clockRegexp.FindStringSubmatch(s); matches != nil {
+		var total time.Duration
+		units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+		for i, unit := range units {
+			if matches[i+1] == "" {
+				continue
+			}
+			value, err := strconv.ParseInt(matches[i+1], 10, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration: %s", s)
+			}
+			total += time.Duration(value) * unit
+		}
+		if matches[6] != "" {
+			fraction, err := strconv.ParseFloat("0"+matches[6], 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration: %s", s)
+			}
+			total += time.Duration(fraction * float64(time.Second))
+		}
+		return total, nil
+	}
+
+	return 0, fmt.Errorf("invalid duration: %s", s)
+}
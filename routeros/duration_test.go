@@ -0,0 +1,47 @@
+package routeros
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    time.Duration
+		wantErr bool
+	}{
+		{value: "1w2d3h4m5s", want: 9*24*time.Hour + 3*time.Hour + 4*time.Minute + 5*time.Second},
+		{value: "2w4d1h12m27s", want: 18*24*time.Hour + time.Hour + 12*time.Minute + 27*time.Second},
+		{value: "36m5s950ms", want: 36*time.Minute + 5*time.Second + 950*time.Millisecond},
+		{value: "500ms", want: 500 * time.Millisecond},
+		{value: "250us", want: 250 * time.Microsecond},
+		{value: "5m", want: 5 * time.Minute},
+		{value: "0s", want: 0},
+		{value: "1d02:03:04", want: 26*time.Hour + 3*time.Minute + 4*time.Second},
+		{value: "1w00:00:01", want: 7*24*time.Hour + time.Second},
+		{value: "00:00:05.5", want: 5500 * time.Millisecond},
+		{value: "", wantErr: true},
+		{value: "5", wantErr: true},
+		{value: "1h2d", wantErr: true},
+		{value: "1:2:3", wantErr: true},
+		{value: "soon", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseDuration(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseDuration(%q) = %v, want error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseDuration(%q) error = %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseDuration(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package routeros
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors returned (wrapped in *Error) by the client
+var (
+	// ErrAuth is returned when the device rejects the credentials
+	ErrAuth = errors.New("authentication failed")
+
+	// ErrNotFound is returned when the requested menu does not exist on the device
+	ErrNotFound = errors.New("not found")
+
+	// ErrTimeout is returned when the request did not complete in time
+	ErrTimeout = errors.New("request timed out")
+
+	// ErrBusy is returned when the device is too busy to answer the request
+	ErrBusy = errors.New("device busy")
+)
+
+// Error describes a failed request against a RouterOS endpoint
+type Error struct {
+	Endpoint   string
+	StatusCode int
+	Detail     string
+	Err        error
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	msg := e.Endpoint + ": "
+	if e.StatusCode != 0 {
+		msg += fmt.Sprintf("HTTP %d: ", e.StatusCode)
+	}
+	msg += e.Err.Error()
+	if e.Detail != "" {
+		msg += " (" + e.Detail + ")"
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error so errors.Is works with the sentinels
+func (e *Error) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,148 @@
+package routeros
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// restTransport talks to the RouterOS REST API over a keep-alive connection pool
+type restTransport struct {
+	target     string
+	auth       Auth
+	httpClient *http.Client
+}
+
+func newRESTTransport(target string, auth Auth) *restTransport {
+	return &restTransport{
+		target: target,
+		auth:   auth,
+		httpClient: &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				DialContext: (&net.Dialer{
+					Timeout:   5 * time.Second,
+					KeepAlive: 30 * time.Second,
+				}).DialContext,
+				TLSClientConfig:     auth.TLS,
+				TLSHandshakeTimeout: 5 * time.Second,
+				MaxIdleConnsPerHost: 4,
+				IdleConnTimeout:     90 * time.Second,
+			},
+		},
+	}
+}
+
+// close closes the idle connections of the transport
+func (t *restTransport) close() {
+	t.httpClient.CloseIdleConnections()
+}
+
+// url returns the URL of a request
+func (t *restTransport) url(r Request) string {
+	u := fmt.Sprintf("%s://%s/rest%s", t.auth.Scheme, t.target, r.Path)
+
+	query := url.Values{}
+	if len(r.Proplist) > 0 {
+		query.Set(".proplist", strings.Join(r.Proplist, ","))
+	}
+	for _, key := range r.filterKeys() {
+		query.Set(key, r.Filter[key])
+	}
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+func (t *restTransport) do(ctx context.Context, r Request) ([]byte, int, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", t.url(r), nil)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	req.SetBasicAuth(t.auth.Username, t.auth.Password)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		if isTimeout(ctx, err) {
+			return nil, 0, &Error{Endpoint: r.Path, Err: ErrTimeout, Detail: err.Error()}
+		}
+		return nil, 0, &Error{Endpoint: r.Path, Err: err}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, resp.StatusCode, statusError(r.Path, resp)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		if isTimeout(ctx, err) {
+			return nil, resp.StatusCode, &Error{Endpoint: r.Path, StatusCode: resp.StatusCode, Err: ErrTimeout, Detail: err.Error()}
+		}
+		return nil, resp.StatusCode, &Error{Endpoint: r.Path, StatusCode: resp.StatusCode, Err: err}
+	}
+
+	return body, resp.StatusCode, nil
+}
+
+// restError is the error body returned by the RouterOS REST API
+type restError struct {
+	Error   int    `json:"error"`
+	Message string `json:"message"`
+	Detail  string `json:"detail"`
+}
+
+// statusError maps a non-200 response to a typed error
+func statusError(path string, resp *http.Response) error {
+	e := &Error{Endpoint: path, StatusCode: resp.StatusCode}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var rerr restError
+	if json.Unmarshal(body, &rerr) == nil {
+		e.Detail = strings.TrimSpace(rerr.Detail)
+		if e.Detail == "" {
+			e.Detail = strings.TrimSpace(rerr.Message)
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden:
+		e.Err = ErrAuth
+	case http.StatusNotFound:
+		e.Err = ErrNotFound
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		e.Err = ErrBusy
+	case http.StatusGatewayTimeout:
+		e.Err = ErrTimeout
+	case http.StatusBadRequest:
+		// RouterOS answers 400 with "no such command" for missing menus
+		if strings.Contains(e.Detail, "no such command") {
+			e.Err = ErrNotFound
+		} else {
+			e.Err = errors.New(resp.Status)
+		}
+	default:
+		e.Err = errors.New(resp.Status)
+	}
+
+	return e
+}
+
+// isTimeout reports whether err was caused by a deadline or network timeout
+func isTimeout(ctx context.Context, err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
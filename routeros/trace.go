@@ -0,0 +1,87 @@
+package routeros
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+// TracedRequest is a request recorded by a Trace, including the raw response
+type TracedRequest struct {
+	RequestStat
+
+	// URL is the request URL, or the command for the native API
+	URL string
+
+	// Body is the response as JSON
+	Body []byte
+}
+
+// ParseError is a property of a response that could not be converted to a metric
+type ParseError struct {
+	Field string
+	Value string
+	Err   error
+}
+
+// Trace records the requests and parse errors of a client for debugging
+type Trace struct {
+	mu          sync.Mutex
+	requests    []TracedRequest
+	parseErrors []ParseError
+}
+
+// Requests returns the recorded requests
+func (t *Trace) Requests() []TracedRequest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]TracedRequest(nil), t.requests...)
+}
+
+// ParseErrors returns the recorded parse errors
+func (t *Trace) ParseErrors() []ParseError {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]ParseError(nil), t.parseErrors...)
+}
+
+func (t *Trace) addRequest(req TracedRequest) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.requests = append(t.requests, req)
+}
+
+func (t *Trace) addParseError(e ParseError) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.parseErrors = append(t.parseErrors, e)
+}
+
+// WithTrace returns a copy of the client that records its requests, the raw
+// responses and parse errors in trace
+func (c *Client) WithTrace(trace *Trace) *Client {
+	clone := *c
+	clone.trace = trace
+	return &clone
+}
+
+// ParseError reports a property that could not be parsed. Collectors skip such
+// values; they are only logged at debug level and recorded by a Trace.
+// Empty values are properties the device doesn't report and are ignored.
+func (c *Client) ParseError(field, value string, err error) {
+	if value == "" {
+		return
+	}
+	if c.trace != nil {
+		c.trace.addParseError(ParseError{Field: field, Value: value, Err: err})
+	}
+	c.logger.Log(context.Background(), slog.LevelDebug, "Failed to parse property",
+		"field", field,
+		"value", value,
+		"err", err,
+	)
+}
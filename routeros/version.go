@@ -0,0 +1,119 @@
+package routeros
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// versionTTL is how long a detected RouterOS version is cached per target
+const versionTTL = time.Hour
+
+// Version is a RouterOS version as reported by /system/resource
+type Version struct {
+	Major int
+	Minor int
+	Raw   string
+}
+
+// AtLeast reports whether the version is greater than or equal to major.minor
+func (v Version) AtLeast(major, minor int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	return v.Minor >= minor
+}
+
+// String returns the version as reported by the device
+func (v Version) String() string {
+	return v.Raw
+}
+
+// ParseVersion parses versions such as "7.15.2 (stable)" or "6.49.10 (long-term)"
+func ParseVersion(s string) (Version, error) {
+	raw := strings.TrimSpace(s)
+	number, _, _ := strings.Cut(raw, " ")
+
+	parts := strings.SplitN(number, ".", 3)
+	if len(parts) < 2 {
+		return Version{}, fmt.Errorf("invalid version: %s", s)
+	}
+
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return Version{}, fmt.Errorf("invalid version: %s", s)
+	}
+
+	// Pre-release versions look like "7.16beta3" or "7.16rc1"
+	minorDigits := parts[1]
+	if i := strings.IndexFunc(minorDigits, func(r rune) bool { return r < '0' || r > '9' }); i >= 0 {
+		minorDigits = minorDigits[:i]
+	}
+	minor, err := strconv.Atoi(minorDigits)
+	if err != nil {
+		return Version{}, fmt.Errorf("invalid version: %s", s)
+	}
+
+	return Version{Major: major, Minor: minor, Raw: raw}, nil
+}
+
+// versionCache remembers the RouterOS version of each target
+type versionCache struct {
+	mu      sync.Mutex
+	entries map[string]versionEntry
+}
+
+type versionEntry struct {
+	version Version
+	expires time.Time
+}
+
+func newVersionCache() *versionCache {
+	return &versionCache{
+		entries: make(map[string]versionEntry),
+	}
+}
+
+func (c *versionCache) get(target string) (Version, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, exists := c.entries[target]
+	if !exists || time.Now().After(entry.expires) {
+		return Version{}, false
+	}
+	return entry.version, true
+}
+
+func (c *versionCache) set(target string, version Version) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[target] = versionEntry{version: version, expires: time.Now().Add(versionTTL)}
+}
+
+// Version returns the RouterOS version of the device. The version is detected
+// from /system/resource once and cached per target.
+func (c *Client) Version(ctx context.Context) (Version, error) {
+	if version, ok := c.versions.get(c.target); ok {
+		return version, nil
+	}
+
+	var resource struct {
+		Version string `json:"version"`
+	}
+	if err := c.Get(ctx, "/system/resource", &resource, Proplist("version")); err != nil {
+		return Version{}, fmt.Errorf("failed to detect RouterOS version: %w", err)
+	}
+
+	version, err := ParseVersion(resource.Version)
+	if err != nil {
+		return Version{}, err
+	}
+
+	c.versions.set(c.target, version)
+	return version, nil
+}
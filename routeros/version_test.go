@@ -0,0 +1,60 @@
+package routeros
+
+import "testing"
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    Version
+		wantErr bool
+	}{
+		{value: "7.15.3 (stable)", want: Version{Major: 7, Minor: 15, Raw: "7.15.3 (stable)"}},
+		{value: "6.49.10 (long-term)", want: Version{Major: 6, Minor: 49, Raw: "6.49.10 (long-term)"}},
+		{value: "7.16rc2", want: Version{Major: 7, Minor: 16, Raw: "7.16rc2"}},
+		{value: "7.16beta3 (testing)", want: Version{Major: 7, Minor: 16, Raw: "7.16beta3 (testing)"}},
+		{value: " 7.1 ", want: Version{Major: 7, Minor: 1, Raw: "7.1"}},
+		{value: "", wantErr: true},
+		{value: "7", wantErr: true},
+		{value: "stable", wantErr: true},
+		{value: "v7.15", wantErr: true},
+		{value: "7.rc1", wantErr: true},
+		{value: "7 .15", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseVersion(%q) = %+v, want error", tt.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseVersion(%q) error = %v", tt.value, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseVersion(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version      Version
+		major, minor int
+		want         bool
+	}{
+		{Version{Major: 7, Minor: 15}, 7, 15, true},
+		{Version{Major: 7, Minor: 15}, 7, 16, false},
+		{Version{Major: 7, Minor: 1}, 6, 49, true},
+		{Version{Major: 6, Minor: 49}, 7, 0, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.version.AtLeast(tt.major, tt.minor); got != tt.want {
+			t.Errorf("Version{%d.%d}.AtLeast(%d, %d) = %v, want %v",
+				tt.version.Major, tt.version.Minor, tt.major, tt.minor, got, tt.want)
+		}
+	}
+}
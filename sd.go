@@ -0,0 +1,151 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/mikrotik-exporter/config"
+	"github.com/mikrotik-exporter/discovery"
+)
+
+// targetGroup is a Prometheus http_sd_config target group
+type targetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// sdHandler serves the configured targets and the devices found by MNDP
+// discovery in the Prometheus http_sd_config format. CIDR ranges are skipped
+// since they don't name a single device.
+//
+// Query parameters:
+//   - module, auth: only return targets using the given module or auth
+//   - label: "name=value", only return targets with that label; can be repeated
+func sdHandler(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	labelFilters := make(map[string]string)
+	for _, filter := range query["label"] {
+		name, value, ok := strings.Cut(filter, "=")
+		if !ok || name == "" {
+			http.Error(w, fmt.Sprintf("Invalid 'label' parameter '%s' (expected name=value)", filter), http.StatusBadRequest)
+			return
+		}
+		labelFilters[name] = value
+	}
+
+	conf := cfg.Load().Config
+	groups := sdTargetGroups(conf, query.Get("module"), query.Get("auth"), labelFilters)
+	if inventory != nil {
+		groups = append(groups, sdDiscoveredGroups(conf, inventory.Devices(), query.Get("module"), query.Get("auth"), labelFilters)...)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(groups); err != nil {
+		slog.Error("Error writing service discovery response", "err", err)
+	}
+}
+
+// sdTargetGroups returns one target group per configured device matching the filters
+func sdTargetGroups(conf *config.Config, module, auth string, labelFilters map[string]string) []targetGroup {
+	groups := []targetGroup{}
+	for _, target := range conf.Targets {
+		if target.IsRange() {
+			continue
+		}
+		if group, ok := newTargetGroup(target.Name, target.Auth, target.Module, target.Labels, module, auth, labelFilters); ok {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// sdDiscoveredGroups returns one target group per discovered device matching
+// the filters. Auth, module and labels are assigned by the discovery rules;
+// devices that are also configured as static targets are skipped.
+func sdDiscoveredGroups(conf *config.Config, devices []discovery.Device, module, auth string, labelFilters map[string]string) []targetGroup {
+	static := make(map[string]bool)
+	for _, target := range conf.Targets {
+		static[target.Name] = true
+		if target.Address != "" {
+			static[target.Address] = true
+		}
+	}
+
+	groups := []targetGroup{}
+	for _, device := range devices {
+		address := device.Address()
+		if address == "" || static[address] {
+			continue
+		}
+
+		rule, _ := conf.MatchDiscoveryRule(device.Board, device.Identity)
+		group, ok := newTargetGroup(address, rule.Auth, rule.Module, rule.Labels, module, auth, labelFilters)
+		if !ok {
+			continue
+		}
+
+		// Probes don't know the rule that matched a discovered device, so its
+		// labels are set on the target group
+		for name, value := range rule.Labels {
+			group.Labels[name] = value
+		}
+		group.Labels["__meta_mndp_identity"] = device.Identity
+		group.Labels["__meta_mndp_board"] = device.Board
+		group.Labels["__meta_mndp_version"] = device.Version
+		group.Labels["__meta_mndp_platform"] = device.Platform
+		group.Labels["__meta_mndp_mac"] = device.MAC
+		group.Labels["__meta_mndp_interface"] = device.Interface
+		group.Labels["__meta_mndp_last_seen"] = device.LastSeen.UTC().Format(time.RFC3339)
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// newTargetGroup builds the target group of a device, filling in the same
+// defaults as a probe would use. It returns false if the device does not
+// match the filters.
+func newTargetGroup(target, targetAuth, targetModule string, targetLabels map[string]string, module, auth string, labelFilters map[string]string) (targetGroup, bool) {
+	if targetAuth == "" {
+		targetAuth = "default"
+	}
+	if targetModule == "" {
+		targetModule = "default"
+	}
+
+	if module != "" && module != targetModule {
+		return targetGroup{}, false
+	}
+	if auth != "" && auth != targetAuth {
+		return targetGroup{}, false
+	}
+	if !matchLabels(targetLabels, labelFilters) {
+		return targetGroup{}, false
+	}
+
+	// The labels are only used to filter: probes add the labels of configured
+	// targets to every metric, and setting them here as well would make
+	// Prometheus rename the scraped ones to exported_*
+	return targetGroup{
+		Targets: []string{target},
+		Labels: map[string]string{
+			"__param_target": target,
+			"__param_auth":   targetAuth,
+			"__param_module": targetModule,
+		},
+	}, true
+}
+
+// matchLabels reports whether labels contain all filters
+func matchLabels(labels, filters map[string]string) bool {
+	for name, value := range filters {
+		if labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
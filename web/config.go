@@ -0,0 +1,199 @@
+package web
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the web configuration of the exporter's own listener, in the
+// style of the Prometheus exporter-toolkit
+type Config struct {
+	TLSServerConfig TLSServerConfig `yaml:"tls_server_config,omitempty"`
+
+	// BasicAuthUsers maps user names to bcrypt password hashes
+	BasicAuthUsers map[string]string `yaml:"basic_auth_users,omitempty"`
+
+	// AdminUsers may run debug probes; they must be basic auth users
+	AdminUsers []string `yaml:"admin_users,omitempty"`
+
+	// AllowedTargets limits the devices /probe connects to. Entries are CIDR
+	// ranges, IP addresses or host names; an empty list allows every target.
+	AllowedTargets []string `yaml:"allowed_targets,omitempty"`
+
+	networks  []*net.IPNet
+	hostnames map[string]bool
+}
+
+// TLSServerConfig enables HTTPS and optionally mutual TLS
+type TLSServerConfig struct {
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+
+	// ClientCAFile enables client certificate verification
+	ClientCAFile string `yaml:"client_ca_file,omitempty"`
+
+	// ClientAuthType is one of the tls.ClientAuthType names, e.g.
+	// RequireAndVerifyClientCert (default when ClientCAFile is set)
+	ClientAuthType string `yaml:"client_auth_type,omitempty"`
+}
+
+// clientAuthTypes maps the names of the client auth types to their values
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"NoClientCert":               tls.NoClientCert,
+	"RequestClientCert":          tls.RequestClientCert,
+	"RequireAnyClientCert":       tls.RequireAnyClientCert,
+	"VerifyClientCertIfGiven":    tls.VerifyClientCertIfGiven,
+	"RequireAndVerifyClientCert": tls.RequireAndVerifyClientCert,
+}
+
+// LoadConfig loads the web configuration from the specified file
+func LoadConfig(filename string) (*Config, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read web config file: %w", err)
+	}
+
+	var config Config
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&config); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to parse web config file: %w", err)
+	}
+
+	if err := config.init(); err != nil {
+		return nil, fmt.Errorf("invalid web config file: %w", err)
+	}
+	return &config, nil
+}
+
+// init validates the configuration and parses the allowed targets
+func (c *Config) init() error {
+	for user, hash := range c.BasicAuthUsers {
+		if !strings.HasPrefix(hash, "$2") {
+			return fmt.Errorf("password of user '%s' is not a bcrypt hash", user)
+		}
+	}
+
+	for _, user := range c.AdminUsers {
+		if _, exists := c.BasicAuthUsers[user]; !exists {
+			return fmt.Errorf("admin user '%s' is not a basic auth user", user)
+		}
+	}
+
+	c.hostnames = make(map[string]bool)
+	for _, target := range c.AllowedTargets {
+		if strings.Contains(target, "/") {
+			_, network, err := net.ParseCIDR(target)
+			if err != nil {
+				return fmt.Errorf("invalid allowed target '%s': %w", target, err)
+			}
+			c.networks = append(c.networks, network)
+			continue
+		}
+		if ip := net.ParseIP(target); ip != nil {
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			c.networks = append(c.networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		c.hostnames[strings.ToLower(target)] = true
+	}
+
+	if c.TLSServerConfig.enabled() {
+		if _, err := c.TLSServerConfig.build(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AllowsTarget reports whether /probe may connect to the given address
+// (host or host:port)
+func (c *Config) AllowsTarget(address string) bool {
+	if len(c.AllowedTargets) == 0 {
+		return true
+	}
+
+	host := address
+	if h, _, err := net.SplitHostPort(address); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+
+	if ip := net.ParseIP(host); ip != nil {
+		for _, network := range c.networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	return c.hostnames[strings.ToLower(host)]
+}
+
+// IsAdmin reports whether an authenticated user is an admin
+func (c *Config) IsAdmin(user string) bool {
+	for _, admin := range c.AdminUsers {
+		if admin == user {
+			return true
+		}
+	}
+	return false
+}
+
+// enabled reports whether HTTPS is configured
+func (t TLSServerConfig) enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+// build creates the tls.Config of the listener
+func (t TLSServerConfig) build() (*tls.Config, error) {
+	if t.CertFile == "" || t.KeyFile == "" {
+		return nil, fmt.Errorf("cert_file and key_file must be set together")
+	}
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load server certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if t.ClientCAFile != "" {
+		pem, err := os.ReadFile(t.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA file '%s'", t.ClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	if t.ClientAuthType != "" {
+		clientAuth, ok := clientAuthTypes[t.ClientAuthType]
+		if !ok {
+			return nil, fmt.Errorf("invalid client_auth_type '%s'", t.ClientAuthType)
+		}
+		if clientAuth >= tls.VerifyClientCertIfGiven && t.ClientCAFile == "" {
+			return nil, fmt.Errorf("client_auth_type '%s' requires client_ca_file", t.ClientAuthType)
+		}
+		tlsConfig.ClientAuth = clientAuth
+	}
+
+	return tlsConfig, nil
+}
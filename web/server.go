@@ -0,0 +1,111 @@
+package web
+
+import (
+	"crypto/sha256"
+	"net/http"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// dummyHash is compared against for unknown users so that the response time
+// doesn't reveal which users exist
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy"), bcrypt.DefaultCost)
+
+// ListenAndServe serves handler on addr, over HTTPS if a certificate is
+// configured. A nil config serves plain HTTP.
+func ListenAndServe(addr string, handler http.Handler, config *Config) error {
+	server := &http.Server{
+		Addr:    addr,
+		Handler: handler,
+	}
+	if config == nil || !config.TLSServerConfig.enabled() {
+		return server.ListenAndServe()
+	}
+
+	tlsConfig, err := config.TLSServerConfig.build()
+	if err != nil {
+		return err
+	}
+	server.TLSConfig = tlsConfig
+	return server.ListenAndServeTLS("", "")
+}
+
+// Authenticator checks basic auth credentials against the configured users.
+// Successful checks are cached since bcrypt is deliberately slow.
+type Authenticator struct {
+	users map[string]string
+
+	mu    sync.Mutex
+	cache map[[sha256.Size]byte]bool
+}
+
+// NewAuthenticator creates an authenticator for the users of the config
+func NewAuthenticator(config *Config) *Authenticator {
+	return &Authenticator{
+		users: config.BasicAuthUsers,
+		cache: make(map[[sha256.Size]byte]bool),
+	}
+}
+
+// Enabled reports whether any users are configured
+func (a *Authenticator) Enabled() bool {
+	return len(a.users) > 0
+}
+
+// Authenticate returns the user name of the request if its credentials are valid
+func (a *Authenticator) Authenticate(r *http.Request) (string, bool) {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		return "", false
+	}
+
+	hash, exists := a.users[user]
+	key := sha256.Sum256([]byte(user + "\x00" + password + "\x00" + hash))
+
+	a.mu.Lock()
+	cached := a.cache[key]
+	a.mu.Unlock()
+	if cached && exists {
+		return user, true
+	}
+
+	if !exists {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return "", false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+		return "", false
+	}
+
+	a.mu.Lock()
+	a.cache[key] = true
+	a.mu.Unlock()
+	return user, true
+}
+
+// Protect requires valid credentials for every request to handler. Paths in
+// public are served without authentication.
+func (a *Authenticator) Protect(handler http.Handler, public ...string) http.Handler {
+	if !a.Enabled() {
+		return handler
+	}
+
+	publicPaths := make(map[string]bool)
+	for _, path := range public {
+		publicPaths[path] = true
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if publicPaths[r.URL.Path] {
+			handler.ServeHTTP(w, r)
+			return
+		}
+		if _, ok := a.Authenticate(r); !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="mikrotik-exporter"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}